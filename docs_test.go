@@ -18,6 +18,8 @@ import (
 func TestTransform(t *testing.T) {
 	for _, format := range []string{
 		"alb",
+		"cloudfront",
+		"nginx",
 	} {
 		format := format
 		t.Run(format, func(t *testing.T) {
@@ -39,6 +41,7 @@ func TestTransform(t *testing.T) {
 				SrcURI:   filepath.Join("docs", "examples", format, "src"),
 				DstURI:   filepath.Join(dir, "dst"),
 				ErrURI:   filepath.Join(dir, "err"),
+				Format:   "auto",
 			}
 
 			err = cmd.Run(base)
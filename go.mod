@@ -8,9 +8,13 @@ require (
 	github.com/alecthomas/units v0.0.0-20210208195552-ff826a37aa15 // indirect
 	github.com/aws/aws-sdk-go v1.38.24
 	github.com/google/uuid v1.2.0
+	github.com/klauspost/compress v1.13.1
 	github.com/mattn/go-isatty v0.0.13
 	github.com/stretchr/testify v1.7.0
 	github.com/ua-parser/uap-go v0.0.0-20210121150957-347a3497cc39
+	github.com/ulikunitz/xz v0.5.10
+	github.com/xitongsys/parquet-go v1.6.2
+	github.com/xitongsys/parquet-go-source v0.0.0-20200817004010-026bad9b25d0
 	go.starlark.net v0.0.0-20210416142453-1607a96e3d72
 	go.uber.org/multierr v1.6.0 // indirect
 	go.uber.org/zap v1.16.0
@@ -2,6 +2,7 @@ package cli
 
 import (
 	"io"
+	"os"
 
 	kingpin "gopkg.in/alecthomas/kingpin.v2"
 
@@ -27,19 +28,41 @@ type command interface {
 	Run(base *cmd.Base) error
 }
 
+// A streamer is a command that may write results to stdout, in which case
+// logs are written to stderr instead.
+type streamer interface {
+	Streaming() bool
+}
+
+// kingpin rejects a bare "-" as an unknown short flag, so it is escaped before
+// parsing, and unescaped by arguments registered with streamVar.
+const escapedStream = "\x00-"
+
 // Parse converts command line arguments into a Command
 func (p *ArgParser) Parse(args []string) (Command, error) {
-	_, err := p.app.Parse(args)
+	escaped := make([]string, len(args))
+	for i, arg := range args {
+		if arg == "-" {
+			arg = escapedStream
+		}
+		escaped[i] = arg
+	}
+	_, err := p.app.Parse(escaped)
 	if err != nil {
 		return nil, err
 	}
 	fn := func(stdout, stderr io.Writer) error {
 		p.base.Verbosity = 1 + p.verbosity - p.brevity
+		logs := stdout
+		if s, ok := p.cmd.(streamer); ok && s.Streaming() {
+			logs = stderr
+		}
 		if p.base.Debug == nil {
-			p.base.Log = logger.New(p.base.Verbosity, stdout, nil)
+			p.base.Log = logger.New(p.base.Verbosity, logs, nil)
 		} else {
-			p.base.Log = logger.New(p.base.Verbosity, stdout, p.base.Debug)
+			p.base.Log = logger.New(p.base.Verbosity, logs, p.base.Debug)
 		}
+		p.base.Stdin = os.Stdin
 		p.base.Stdout = stdout
 		p.base.Stderr = stderr
 		p.base.Log.Infof("carpenter version=%s", p.version)
@@ -56,3 +79,24 @@ func (p *ArgParser) addCommand(cmd command, name, help string) *kingpin.CmdClaus
 	})
 	return clause
 }
+
+// streamVar registers an argument that may be "-".
+func streamVar(arg *kingpin.ArgClause, target *string) {
+	arg.SetValue(&streamValue{target})
+}
+
+type streamValue struct {
+	s *string
+}
+
+func (v *streamValue) Set(s string) error {
+	if s == escapedStream {
+		s = "-"
+	}
+	*v.s = s
+	return nil
+}
+
+func (v *streamValue) String() string {
+	return *v.s
+}
@@ -34,3 +34,38 @@ func TestArgParser(t *testing.T) {
 		}
 	}
 }
+
+func TestTransformStreamArgs(t *testing.T) {
+	require := require.New(t)
+
+	for _, tc := range []struct {
+		args []string
+		src  string
+		dst  string
+	}{{
+		args: []string{"transform", "patterns.star", "-", "-"},
+		src:  "-",
+		dst:  "-",
+	}, {
+		args: []string{"transform", "--output-format=json", "patterns.star", "-", "-", "errors"},
+		src:  "-",
+		dst:  "-",
+	}, {
+		args: []string{"transform", "patterns.star", "--", "-", "-"},
+		src:  "-",
+		dst:  "-",
+	}, {
+		args: []string{"transform", "patterns.star", "-", "dst", "--columns", "a,b"},
+		src:  "-",
+		dst:  "dst",
+	}} {
+		parser := RegisterCommands("test")
+		_, err := parser.Parse(tc.args)
+		require.NoError(err, tc.args)
+
+		c, ok := parser.cmd.(*cmd.TransformCmd)
+		require.True(ok, tc.args)
+		require.Equal(tc.src, c.SrcURI, tc.args)
+		require.Equal(tc.dst, c.DstURI, tc.args)
+	}
+}
@@ -0,0 +1,12 @@
+package cli
+
+import "github.com/sjansen/carpenter/internal/cmd"
+
+func registerExplain(p *ArgParser) {
+	c := &cmd.ExplainCmd{}
+	cmd := p.addCommand(c, "explain", "Trace how a URL is matched by a pattern file")
+	cmd.Arg("PATTERNS", "Pattern file").Required().
+		StringVar(&c.Patterns)
+	cmd.Arg("URL", "URL to match").Required().
+		StringVar(&c.URL)
+}
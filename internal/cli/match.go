@@ -0,0 +1,14 @@
+package cli
+
+import "github.com/sjansen/carpenter/internal/cmd"
+
+func registerMatch(p *ArgParser) {
+	c := &cmd.MatchCmd{}
+	cmd := p.addCommand(c, "match", "Print the pattern and normalized URL of each URL")
+	cmd.Arg("PATTERNS", "Pattern file").Required().
+		StringVar(&c.Patterns)
+	cmd.Arg("URL", "URLs to match (default: read one per line from stdin)").
+		StringsVar(&c.URLs)
+	cmd.Flag("all", "print every matching pattern, not just the first").
+		BoolVar(&c.All)
+}
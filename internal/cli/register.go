@@ -28,7 +28,10 @@ func RegisterCommands(version string) *ArgParser {
 		Short('v').CounterVar(&parser.verbosity)
 
 	registerVersion(parser, version)
+	registerExplain(parser)
+	registerMatch(parser)
 	registerTest(parser)
+	registerSuggest(parser)
 	registerTestCases(parser)
 	registerTransform(parser)
 	return parser
@@ -0,0 +1,16 @@
+package cli
+
+import "github.com/sjansen/carpenter/internal/cmd"
+
+func registerSuggest(p *ArgParser) {
+	c := &cmd.SuggestCmd{}
+	cmd := p.addCommand(c, "suggest", "Suggest patterns for paths grouped by shape")
+	cmd.Arg("FILE", "unrecognized error files (*.csv or *.json), or raw logs").Required().
+		ExistingFilesVar(&c.Files)
+	cmd.Flag("patterns", "skip URLs in raw logs matched by a pattern file").
+		PlaceHolder("FILE").StringVar(&c.Patterns)
+	cmd.Flag("min-count", "only suggest patterns for shapes with at least this many paths").
+		Default("1").IntVar(&c.MinCount)
+	cmd.Flag("samples", "number of paths used as tests by each suggestion").
+		Default("3").IntVar(&c.Samples)
+}
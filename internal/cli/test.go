@@ -7,4 +7,6 @@ func registerTest(p *ArgParser) {
 	cmd := p.addCommand(c, "test", "Evaluate pattern file self tests")
 	cmd.Arg("FILE", "A pattern file").Required().
 		ExistingFileVar(&c.File)
+	cmd.Flag("junit", "also write results as JUnit XML").
+		PlaceHolder("FILE").StringVar(&c.JUnit)
 }
@@ -1,16 +1,68 @@
 package cli
 
-import "github.com/sjansen/carpenter/internal/cmd"
+import (
+	"github.com/sjansen/carpenter/internal/cmd"
+	"github.com/sjansen/carpenter/internal/lazyio"
+	"github.com/sjansen/carpenter/internal/tokenizer"
+)
 
 func registerTransform(p *ArgParser) {
-	c := &cmd.TransformCmd{}
+	c := &cmd.TransformCmd{
+		JSONFields: map[string]string{},
+	}
 	cmd := p.addCommand(c, "transform", "TODO")
 	cmd.Arg("PATTERNS", "Pattern file").Required().
 		StringVar(&c.Patterns)
-	cmd.Arg("SRC", "Source directory").Required().
-		StringVar(&c.SrcURI)
-	cmd.Arg("DST", "Target directory").Required().
-		StringVar(&c.DstURI)
+	streamVar(cmd.Arg("SRC", "Source directory, or - to read a single input from stdin").Required(),
+		&c.SrcURI)
+	streamVar(cmd.Arg("DST", "Target directory, or - to write results to stdout (requires SRC -)").Required(),
+		&c.DstURI)
 	cmd.Arg("ERRORS", "Errors directory").
 		StringVar(&c.ErrURI)
+	cmd.Flag("format", "log format, or \"auto\" to detect the format of each input"+
+		" (default: the pattern file's tokenizer, or alb)").
+		EnumVar(&c.Format, append(tokenizer.Formats(), "auto")...)
+	cmd.Flag("log-format", "custom nginx log_format string, requires --format=nginx").
+		StringVar(&c.LogFormat)
+	cmd.Flag("json-field", "rename a flattened JSON field, e.g. http.url=request_url, requires --format=json").
+		PlaceHolder("PATH=NAME").StringMapVar(&c.JSONFields)
+	cmd.Flag("columns", "comma-separated result columns; undeclared fields are dropped"+
+		" (default: the pattern file's columns, or the fields of the first record)").
+		PlaceHolder("COL,...").StringVar(&c.Columns)
+	cmd.Flag("fail-fast", "cancel remaining inputs after the first failure").
+		BoolVar(&c.FailFast)
+	cmd.Flag("include", "only transform inputs matching a glob, e.g. **/2021/01/*/*.log.gz;"+
+		" archive members are matched as ARCHIVE!/MEMBER").
+		PlaceHolder("GLOB").StringsVar(&c.Include)
+	cmd.Flag("exclude", "skip inputs matching a glob, and archives without extracting them").
+		PlaceHolder("GLOB").StringsVar(&c.Exclude)
+	cmd.Flag("since", "skip inputs dated before a date or RFC 3339 time,"+
+		" using the date in the input's path or else its modification time").
+		StringVar(&c.Since)
+	cmd.Flag("until", "skip inputs dated at or after a date or RFC 3339 time").
+		StringVar(&c.Until)
+	cmd.Flag("resume", "skip inputs recorded as complete and unchanged in DST's manifest,"+
+		" and record newly completed inputs").
+		BoolVar(&c.Resume)
+	cmd.Flag("follow", "keep polling SRC and transform new inputs as they appear, until interrupted;"+
+		" failed inputs are retried on the next poll").
+		BoolVar(&c.Follow)
+	cmd.Flag("poll-interval", "time between polls of SRC when following").
+		Default("1m").DurationVar(&c.PollInterval)
+	cmd.Flag("state", "local file recording completed inputs, so they are skipped"+
+		" when the transform is restarted").
+		PlaceHolder("FILE").StringVar(&c.State)
+	cmd.Flag("coverage", "write a report of the records matched by each pattern,"+
+		" patterns that never matched, and the most frequent unrecognized paths").
+		PlaceHolder("FILE").StringVar(&c.Coverage)
+	cmd.Flag("workers", "number of inputs to transform concurrently (default: number of CPUs)").
+		IntVar(&c.Workers)
+	cmd.Flag("max-open-outputs", "limit on result and error files open at once (default: no limit)").
+		IntVar(&c.MaxOpenOutputs)
+	cmd.Flag("output-format", "format of result files").
+		Default("csv").EnumVar(&c.OutputFormat, "csv", "json", "parquet")
+	cmd.Flag("compress", "compress result and error files").
+		EnumVar(&c.Compress, lazyio.Compressions...)
+	cmd.Flag("errors-format", "format of error files").
+		Default("csv").EnumVar(&c.ErrorsFormat, "csv", "json")
 }
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+type ExplainCmd struct {
+	Patterns string
+	URL      string
+}
+
+// Run prints each step taken to match the URL, followed by the result.
+func (c *ExplainCmd) Run(base *Base) error {
+	patterns, err := loadPatterns(context.Background(), &base.IO, c.Patterns)
+	if err != nil {
+		return err
+	}
+
+	url, err := url.Parse(c.URL)
+	if err != nil {
+		return err
+	}
+	id, normalized, err := patterns.Explain(base.Stdout, url)
+	if err != nil {
+		return err
+	} else if id == "" {
+		return fmt.Errorf("error: no pattern matched %q", c.URL)
+	}
+	fmt.Fprintf(base.Stdout, "result: pattern=%q normalized=%q\n", id, normalized)
+	return nil
+}
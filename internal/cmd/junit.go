@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"encoding/xml"
+	"os"
+	"sort"
+
+	"github.com/sjansen/carpenter/internal/patterns"
+)
+
+type junitSuites struct {
+	XMLName  xml.Name      `xml:"testsuites"`
+	Name     string        `xml:"name,attr"`
+	Tests    int           `xml:"tests,attr"`
+	Failures int           `xml:"failures,attr"`
+	Suites   []*junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Cases    []*junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Type    string `xml:"type,attr"`
+	Message string `xml:"message,attr"`
+}
+
+// writeJUnit writes the results as JUnit XML, with a test suite for each
+// pattern and a test case for each URL.
+func writeJUnit(path, name string, results *patterns.TestResults) error {
+	report := &junitSuites{Name: name}
+	suites := map[string]*junitSuite{}
+	for _, c := range results.Cases {
+		suite, ok := suites[c.Pattern]
+		if !ok {
+			suite = &junitSuite{Name: c.Pattern}
+			suites[c.Pattern] = suite
+			report.Suites = append(report.Suites, suite)
+		}
+		tc := &junitCase{
+			Name:      c.URL,
+			Classname: c.Pattern,
+		}
+		if c.Failure != "" {
+			tc.Failure = &junitFailure{
+				Type:    c.Failure,
+				Message: c.Err.Error(),
+			}
+			suite.Failures++
+			report.Failures++
+		}
+		suite.Cases = append(suite.Cases, tc)
+		suite.Tests++
+		report.Tests++
+	}
+	sort.Slice(report.Suites, func(i, j int) bool {
+		return report.Suites[i].Name < report.Suites[j].Name
+	})
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(xml.Header); err != nil {
+		f.Close()
+		return err
+	}
+	enc := xml.NewEncoder(f)
+	enc.Indent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.WriteString("\n"); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
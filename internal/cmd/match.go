@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/sjansen/carpenter/internal/patterns"
+)
+
+type MatchCmd struct {
+	Patterns string
+	URLs     []string
+	All      bool
+}
+
+// Run prints the URL, pattern id, and normalized URL of each match, one per
+// line and separated by tabs. URLs are read from stdin when none are given.
+func (c *MatchCmd) Run(base *Base) error {
+	patterns, err := loadPatterns(context.Background(), &base.IO, c.Patterns)
+	if err != nil {
+		return err
+	}
+
+	total, unmatched := 0, 0
+	match := func(rawurl string) {
+		total++
+		ok, err := c.match(base.Stdout, patterns, rawurl)
+		switch {
+		case err != nil:
+			fmt.Fprintf(base.Stderr, "FAILED: %s: %s\n", rawurl, err)
+		case !ok:
+			fmt.Fprintln(base.Stderr, "UNMATCHED:", rawurl)
+		}
+		if !ok {
+			unmatched++
+		}
+	}
+
+	if len(c.URLs) > 0 {
+		for _, rawurl := range c.URLs {
+			match(rawurl)
+		}
+	} else {
+		scanner := bufio.NewScanner(base.Stdin)
+		for scanner.Scan() {
+			if rawurl := strings.TrimSpace(scanner.Text()); rawurl != "" {
+				match(rawurl)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+	}
+
+	if unmatched > 0 {
+		return fmt.Errorf("error: %d of %d URLs unmatched", unmatched, total)
+	}
+	return nil
+}
+
+func (c *MatchCmd) match(w io.Writer, patterns *patterns.Patterns, rawurl string) (bool, error) {
+	url, err := url.Parse(rawurl)
+	if err != nil {
+		return false, err
+	}
+
+	if !c.All {
+		id, normalized, err := patterns.Match(url)
+		if err != nil || id == "" {
+			return false, err
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", rawurl, id, normalized)
+		return true, nil
+	}
+
+	matches, err := patterns.MatchAll(url)
+	if err != nil || len(matches) < 1 {
+		return false, err
+	}
+	ids := make([]string, 0, len(matches))
+	for id := range matches {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", rawurl, id, matches[id])
+	}
+	return true, nil
+}
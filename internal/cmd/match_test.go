@@ -0,0 +1,87 @@
+package cmd_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/sjansen/carpenter/internal/cmd"
+	"github.com/sjansen/carpenter/internal/logger"
+	"github.com/sjansen/carpenter/internal/sys"
+)
+
+func TestMatch(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		cmd      cmd.MatchCmd
+		stdin    string
+		expected string
+		stderr   string
+	}{{
+		name: "args",
+		cmd: cmd.MatchCmd{
+			URLs: []string{"/", "https://www.example.com/search/?q=apples"},
+		},
+		expected: "/\troot\t/\n" +
+			"https://www.example.com/search/?q=apples\tsearch\t/search?q=X\n",
+	}, {
+		name:  "stdin",
+		stdin: "/search?q=oranges\n\n/.well-known/security.txt\n",
+		expected: "/search?q=oranges\tsearch\t/search?q=X\n" +
+			"/.well-known/security.txt\techo\t/.well-known/security.txt\n",
+	}, {
+		name: "first",
+		cmd: cmd.MatchCmd{
+			Patterns: "testdata/overlap.star",
+			URLs:     []string{"/foo/"},
+		},
+		expected: "/foo/\tfirst\t/foo/\n",
+	}, {
+		name: "all",
+		cmd: cmd.MatchCmd{
+			Patterns: "testdata/overlap.star",
+			URLs:     []string{"/foo/"},
+			All:      true,
+		},
+		expected: "/foo/\tfirst\t/foo/\n" +
+			"/foo/\tsecond\t/ANY/\n",
+	}, {
+		name: "unmatched",
+		cmd: cmd.MatchCmd{
+			Patterns: "testdata/overlap.star",
+			URLs:     []string{"/foo/", "/", "foo"},
+		},
+		expected: "/foo/\tfirst\t/foo/\n",
+		stderr: "UNMATCHED: /\n" +
+			`FAILED: foo: URLs must start with "/": "foo"` + "\n",
+	}} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			var stdout, stderr bytes.Buffer
+			base := &cmd.Base{
+				IO: sys.IO{
+					Log:    logger.Discard(),
+					Stdin:  strings.NewReader(tc.stdin),
+					Stdout: &stdout,
+					Stderr: &stderr,
+				},
+			}
+			c := tc.cmd
+			if c.Patterns == "" {
+				c.Patterns = "../../docs/examples/example.star"
+			}
+			err := c.Run(base)
+			if tc.stderr == "" {
+				require.NoError(err)
+			} else {
+				require.Error(err)
+			}
+			require.Equal(tc.expected, stdout.String())
+			require.Equal(tc.stderr, stderr.String())
+		})
+	}
+}
@@ -0,0 +1,239 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	pathlib "path"
+	"path/filepath"
+	"strings"
+
+	"github.com/sjansen/carpenter/internal/lazyio"
+	"github.com/sjansen/carpenter/internal/patterns"
+	"github.com/sjansen/carpenter/internal/shapes"
+	"github.com/sjansen/carpenter/internal/tokenizer"
+)
+
+// suggestDetectLines is the number of lines used to detect the format of a
+// raw log.
+const suggestDetectLines = 10
+
+type SuggestCmd struct {
+	Files    []string
+	Patterns string
+	MinCount int
+	Samples  int
+}
+
+// Run prints a url() stanza for each group of paths with the same shape.
+func (c *SuggestCmd) Run(base *Base) error {
+	ctx := context.Background()
+	var known *patterns.Patterns
+	if c.Patterns != "" {
+		var err error
+		known, err = loadPatterns(ctx, &base.IO, c.Patterns)
+		if err != nil {
+			return err
+		}
+	}
+
+	grouper := &shapes.Grouper{Samples: c.Samples}
+	for _, file := range c.Files {
+		base.Log.Debugw("reading paths", "file", file)
+		if err := readPaths(ctx, file, known, grouper.Add); err != nil {
+			return err
+		}
+	}
+
+	first := true
+	for _, group := range grouper.Groups() {
+		if group.Count < c.MinCount || !suggestable(group.Shape) {
+			continue
+		}
+		if !first {
+			fmt.Fprintln(base.Stdout)
+		}
+		first = false
+		writeStanza(base.Stdout, group)
+	}
+	return nil
+}
+
+// readPaths calls fn with each path in an unrecognized debug output, or each
+// path in a raw log that isn't matched by known.
+func readPaths(ctx context.Context, file string, known *patterns.Patterns, fn func(string)) error {
+	input := &lazyio.Input{
+		Path:   filepath.Base(file),
+		Opener: &lazyio.FileReader{Dir: filepath.Dir(file)},
+	}
+	defer input.Close()
+	r, err := input.Open(ctx)
+	if err != nil {
+		return err
+	}
+
+	switch pathlib.Ext(lazyio.StripCompressionExt(file)) {
+	case ".csv":
+		return readPathsCSV(r, fn)
+	case ".json":
+		return readPathsJSON(r, fn)
+	}
+	return readPathsLog(file, r, known, fn)
+}
+
+func readPathsCSV(r io.Reader, fn func(string)) error {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if len(record) > 0 {
+			fn(record[0])
+		}
+	}
+}
+
+func readPathsJSON(r io.Reader, fn func(string)) error {
+	dec := json.NewDecoder(r)
+	for {
+		var record map[string]string
+		err := dec.Decode(&record)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if path, ok := record["path"]; ok {
+			fn(path)
+		}
+	}
+}
+
+func readPathsLog(file string, r io.Reader, known *patterns.Patterns, fn func(string)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var sample []string
+	for len(sample) < suggestDetectLines && scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			sample = append(sample, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	} else if len(sample) < 1 {
+		return nil
+	}
+	_, tk := tokenizer.Detect(sample)
+	if tk == nil {
+		return fmt.Errorf("error: unable to detect log format: %q", file)
+	}
+	tk = tokenizer.Clone(tk)
+	stateful, _ := tk.(tokenizer.Stateful)
+
+	add := func(line string) {
+		if line == "" || stateful != nil && stateful.Header(line) {
+			return
+		}
+		tokens := tk.Tokenize(line)
+		url, err := url.Parse(tokens["request_url"])
+		if err != nil || url.Path == "" {
+			return
+		}
+		if known != nil {
+			if id, _, err := known.Match(url); err == nil && id != "" {
+				return
+			}
+		}
+		fn(url.Path)
+	}
+	for _, line := range sample {
+		add(line)
+	}
+	for scanner.Scan() {
+		add(strings.TrimSpace(scanner.Text()))
+	}
+	return scanner.Err()
+}
+
+// suggestable returns true if shape has at least one segment and no empty
+// segments. The root path can't be suggested, since patterns with no prefix
+// can't match it.
+func suggestable(shape string) bool {
+	if !strings.HasPrefix(shape, "/") || shape == "/" {
+		return false
+	}
+	for _, segment := range strings.Split(shape[1:], "/") {
+		if segment == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// writeStanza writes a url() stanza matching the paths of group, using its
+// samples as tests.
+func writeStanza(w io.Writer, group *shapes.Group) {
+	segments := strings.Split(group.Shape[1:], "/")
+	prefix := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		if match, reject, ok := shapes.Regex(segment); ok {
+			name := strings.ToUpper(strings.Trim(segment, "{}"))
+			if reject == "" {
+				prefix = append(prefix, fmt.Sprintf("(%q, %q)", match, name))
+			} else {
+				prefix = append(prefix, fmt.Sprintf("(%q, %q, %q)", match, name, reject))
+			}
+		} else {
+			prefix = append(prefix, fmt.Sprintf("%q", segment))
+		}
+	}
+
+	var suffix string
+	switch {
+	case group.Slash && group.NoSlash:
+		suffix = "/?"
+	case group.Slash:
+		suffix = "/"
+	}
+
+	fmt.Fprintf(w, "# paths: %d\n", group.Count)
+	fmt.Fprintf(w, "url(\n")
+	fmt.Fprintf(w, "    %q,\n", group.Shape)
+	fmt.Fprintf(w, "    path = {\n")
+	fmt.Fprintf(w, "        \"prefix\": [%s],\n", strings.Join(prefix, ", "))
+	fmt.Fprintf(w, "        \"suffix\": %q,\n", suffix)
+	fmt.Fprintf(w, "    },\n")
+	fmt.Fprintf(w, "    query = {},\n")
+	fmt.Fprintf(w, "    tests = {\n")
+	for _, sample := range group.Samples {
+		fmt.Fprintf(w, "        %q: %q,\n", sample, normalizeSample(sample, suffix))
+	}
+	fmt.Fprintf(w, "    },\n")
+	fmt.Fprintf(w, ")\n")
+}
+
+// normalizeSample returns the path replacing each variable segment with the
+// name of its shape, as the suggested stanza would.
+func normalizeSample(path, suffix string) string {
+	segments := strings.Split(strings.TrimSuffix(path[1:], "/"), "/")
+	for i, segment := range segments {
+		shape := shapes.Segment(segment)
+		if _, _, ok := shapes.Regex(shape); ok {
+			segments[i] = strings.ToUpper(strings.Trim(shape, "{}"))
+		}
+	}
+	normalized := "/" + strings.Join(segments, "/")
+	if suffix == "/" {
+		normalized += "/"
+	}
+	return normalized
+}
@@ -0,0 +1,77 @@
+package cmd_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/sjansen/carpenter/internal/cmd"
+	"github.com/sjansen/carpenter/internal/logger"
+	"github.com/sjansen/carpenter/internal/patterns"
+	"github.com/sjansen/carpenter/internal/sys"
+)
+
+func TestSuggest(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		cmd      cmd.SuggestCmd
+		expected []string
+	}{{
+		name: "unrecognized",
+		cmd: cmd.SuggestCmd{
+			Files: []string{"testdata/unrecognized.csv"},
+		},
+		expected: []string{
+			"/users/{int}",
+			"/blobs/{hex}",
+			"/users/{int}/posts/{slug}",
+			"/users/{int}/posts/{uuid}",
+		},
+	}, {
+		name: "min-count",
+		cmd: cmd.SuggestCmd{
+			Files:    []string{"testdata/unrecognized.csv"},
+			MinCount: 2,
+		},
+		expected: []string{"/users/{int}"},
+	}, {
+		name: "conflicting",
+		cmd: cmd.SuggestCmd{
+			Files: []string{"testdata/conflicting.csv"},
+		},
+		expected: []string{
+			"/blobs/{hex}",
+			"/blobs/deadbeef",
+		},
+	}, {
+		name: "logs",
+		cmd: cmd.SuggestCmd{
+			Files:    []string{"../../docs/examples/alb/src/example.log"},
+			Patterns: "../../docs/examples/swapi.star",
+		},
+		expected: []string{"/favicon.ico"},
+	}} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			var stdout, stderr bytes.Buffer
+			base := &cmd.Base{
+				IO: sys.IO{
+					Log:    logger.Discard(),
+					Stdout: &stdout,
+					Stderr: &stderr,
+				},
+			}
+			c := tc.cmd
+			c.Samples = 3
+			require.NoError(c.Run(base))
+
+			suggested, err := patterns.Load("suggested.star", &stdout)
+			require.NoError(err)
+			require.Equal(tc.expected, suggested.IDs())
+			require.NoError(suggested.RunTests(&base.IO).Err())
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"fmt"
+	"io"
 	"os"
 
 	"github.com/sjansen/carpenter/internal/patterns"
@@ -8,9 +10,11 @@ import (
 
 type TestCmd struct {
 	File    string
+	JUnit   string
 	Verbose bool
 }
 
+// Run prints the failed tests grouped by kind of failure, and a summary.
 func (c *TestCmd) Run(base *Base) error {
 	r, err := os.Open(c.File)
 	if err != nil {
@@ -22,6 +26,40 @@ func (c *TestCmd) Run(base *Base) error {
 		return err
 	}
 
-	_, err = patterns.Test(&base.IO)
-	return err
+	results := patterns.RunTests(&base.IO)
+	failures := results.Failures()
+	printFailures(base.Stdout, failures)
+	fmt.Fprintf(base.Stdout, "%d passed, %d failed\n",
+		len(results.Cases)-len(failures), len(failures),
+	)
+
+	if c.JUnit != "" {
+		if err := writeJUnit(c.JUnit, c.File, results); err != nil {
+			return err
+		}
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("error: %d of %d tests failed", len(failures), len(results.Cases))
+	}
+	return nil
+}
+
+func printFailures(w io.Writer, failures []*patterns.TestCase) {
+	for _, kind := range patterns.FailureKinds {
+		var group []*patterns.TestCase
+		for _, c := range failures {
+			if c.Failure == kind {
+				group = append(group, c)
+			}
+		}
+		if len(group) < 1 {
+			continue
+		}
+		fmt.Fprintf(w, "%s (%d):\n", kind, len(group))
+		for _, c := range group {
+			fmt.Fprintf(w, "  %s\n", c.Err)
+		}
+		fmt.Fprintln(w)
+	}
 }
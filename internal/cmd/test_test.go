@@ -0,0 +1,75 @@
+package cmd_test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/sjansen/carpenter/internal/cmd"
+	"github.com/sjansen/carpenter/internal/logger"
+	"github.com/sjansen/carpenter/internal/sys"
+)
+
+func TestTest(t *testing.T) {
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "carpenter-")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	var stdout, stderr bytes.Buffer
+	base := &cmd.Base{
+		IO: sys.IO{
+			Log:    logger.Discard(),
+			Stdout: &stdout,
+			Stderr: &stderr,
+		},
+	}
+	c := &cmd.TestCmd{
+		File:  "testdata/failing.star",
+		JUnit: filepath.Join(dir, "junit.xml"),
+	}
+	err = c.Run(base)
+	require.EqualError(err, "error: 5 of 7 tests failed")
+
+	expected := `no match (2):
+  url didn't match expected pattern: url="/foo" pattern="first"
+  url didn't match expected pattern: url="/qux/" pattern="first"
+
+multiple matches (1):
+  url matched by multiple patterns: url="/bar/" patterns=["second" "third"]
+
+wrong pattern (1):
+  unexpected pattern matched: expected="first" actual="second" (url="/boo")
+
+wrong normalization (1):
+  unexpected result: expected="/baz" actual="/ANY" (pattern="second" url="/baz")
+
+2 passed, 5 failed
+`
+	require.Equal(expected, stdout.String())
+
+	data, err := ioutil.ReadFile(c.JUnit)
+	require.NoError(err)
+	var report struct {
+		Tests    int `xml:"tests,attr"`
+		Failures int `xml:"failures,attr"`
+		Suites   []struct {
+			Name     string `xml:"name,attr"`
+			Tests    int    `xml:"tests,attr"`
+			Failures int    `xml:"failures,attr"`
+		} `xml:"testsuite"`
+	}
+	require.NoError(xml.Unmarshal(data, &report))
+	require.Equal(7, report.Tests)
+	require.Equal(5, report.Failures)
+	require.Len(report.Suites, 3)
+	require.Equal("first", report.Suites[0].Name)
+	require.Equal(4, report.Suites[0].Tests)
+	require.Equal(3, report.Suites[0].Failures)
+}
@@ -1,12 +1,18 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/s3"
 
 	"github.com/sjansen/carpenter/internal/lazyio"
@@ -19,78 +25,413 @@ import (
 )
 
 type TransformCmd struct {
-	Patterns string
-	SrcURI   string
-	DstURI   string
-	ErrURI   string
+	Patterns   string
+	Columns    string
+	SrcURI     string
+	DstURI     string
+	ErrURI     string
+	Format     string
+	LogFormat  string
+	JSONFields map[string]string
+
+	OutputFormat string
+	ErrorsFormat string
+	Compress     string
+	FailFast     bool
+
+	Workers        int
+	MaxOpenOutputs int
+
+	Resume bool
+
+	Follow       bool
+	PollInterval time.Duration
+	State        string
+
+	Include []string
+	Exclude []string
+	Since   string
+	Until   string
+
+	Coverage string
+}
+
+// coverageTop is the number of unrecognized path shapes in coverage reports.
+const coverageTop = 20
+
+// stream is the SRC or DST of a transform using stdin or stdout.
+const stream = "-"
+
+// Streaming returns true if results are written to stdout.
+func (c *TransformCmd) Streaming() bool {
+	return c.DstURI == stream
 }
 
 func (c *TransformCmd) Run(base *Base) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		// restore the default handlers, so a second signal kills a shutdown
+		// that hangs
+		<-ctx.Done()
+		stop()
+	}()
+
 	io := &base.IO
 	log := io.Log
 	log.Debugw("creating pipeline")
-	pipeline, walker, err := c.newPipeline(io)
+	p, walker, archives, err := c.newPipeline(ctx, io)
 	if err != nil {
 		return err
 	}
+	defer archives.Close()
+	if c.Coverage != "" {
+		p.Coverage = &pipeline.Coverage{}
+	}
 
 	log.Debugw("starting pipeline")
-	pipeline.Start()
-	defer log.Debugw("pipeline finished")
-	defer pipeline.Wait()
-	defer log.Debugw("waiting for pipeline to finish")
-
-	return walker.Walk(func(path string) error {
-		log.Debugw("adding task to pipeline", "path", path)
-		pipeline.AddTask(path)
+	p.Start(ctx)
+
+	err = walker.Walk(ctx, func(entry lazyio.Entry) error {
+		log.Debugw("adding task to pipeline", "path", entry.Path)
+		err := p.AddTask(ctx, entry)
+		if err != nil && (c.FailFast || ctx.Err() != nil) {
+			return err
+		}
 		return nil
 	})
+
+	log.Debugw("waiting for pipeline to finish")
+	summary := p.Wait()
+	log.Debugw("pipeline finished")
+
+	// save progress even when interrupted, so the transform can be resumed
+	if err := p.Manifest.Save(context.Background()); err != nil {
+		return err
+	}
+	if summary.Skipped > 0 {
+		log.Infow("skipped complete inputs", "count", summary.Skipped)
+	}
+	if c.Coverage != "" {
+		if err := c.writeCoverage(p); err != nil {
+			return err
+		}
+	}
+
+	// following only stops when interrupted
+	if c.Follow && errors.Is(err, context.Canceled) {
+		err = nil
+	}
+	interrupted := 0
+	for _, failure := range summary.Failures {
+		if errors.Is(failure, context.Canceled) {
+			interrupted++
+			continue
+		}
+		fmt.Fprintln(io.Stderr, "FAILED:", failure)
+	}
+	incomplete := interrupted + summary.Canceled
+	switch {
+	case ctx.Err() != nil && (incomplete > 0 || !c.Follow):
+		return fmt.Errorf(
+			"error: interrupted, %d of %d inputs incomplete",
+			incomplete, summary.Tasks,
+		)
+	case err != nil && !errors.Is(err, pipeline.ErrCanceled):
+		return err
+	case len(summary.Failures) > 0 && summary.Canceled > 0:
+		return fmt.Errorf(
+			"error: %d of %d inputs failed (%d canceled)",
+			len(summary.Failures), summary.Tasks, summary.Canceled,
+		)
+	case len(summary.Failures) > 0:
+		return fmt.Errorf(
+			"error: %d of %d inputs failed", len(summary.Failures), summary.Tasks,
+		)
+	}
+	return nil
 }
 
-func (c *TransformCmd) newPipeline(io *sys.IO) (*pipeline.Pipeline, lazyio.InputWalker, error) {
+func (c *TransformCmd) newPipeline(ctx context.Context, io *sys.IO) (*pipeline.Pipeline, lazyio.InputWalker, *lazyio.ArchiveReader, error) {
+	switch {
+	case c.DstURI == stream && c.SrcURI != stream:
+		return nil, nil, nil, fmt.Errorf("error: DST can only be %q when SRC is %q", stream, stream)
+	case c.ErrURI == stream:
+		return nil, nil, nil, fmt.Errorf("error: ERRORS can't be %q", stream)
+	case c.DstURI == stream && c.Resume:
+		return nil, nil, nil, fmt.Errorf("error: --resume can't be combined with DST %q", stream)
+	case c.SrcURI == stream && c.Follow:
+		return nil, nil, nil, fmt.Errorf("error: --follow can't be combined with SRC %q", stream)
+	case c.SrcURI == stream && (c.Since != "" || c.Until != ""):
+		return nil, nil, nil, fmt.Errorf("error: --since and --until can't be combined with SRC %q", stream)
+	case c.SrcURI == stream && (len(c.Include) > 0 || len(c.Exclude) > 0):
+		return nil, nil, nil, fmt.Errorf("error: --include and --exclude can't be combined with SRC %q", stream)
+	case c.Resume && c.State != "":
+		return nil, nil, nil, fmt.Errorf("error: --resume can't be combined with --state")
+	case c.Follow && c.PollInterval <= 0:
+		return nil, nil, nil, fmt.Errorf("error: --poll-interval must be positive")
+	}
+
 	log := io.Log
-	patterns, err := loadPatterns(io, c.Patterns)
+	patterns, err := loadPatterns(ctx, io, c.Patterns)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	log.Debugw("loading user-agent parser")
 	uaparser, err := uaparser.UserAgentParser()
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	pipeline := &pipeline.Pipeline{
-		IO:        io,
-		Patterns:  patterns,
-		Tokenizer: tokenizer.ALB,
-		UAParser:  uaparser,
+		IO:           io,
+		Patterns:     patterns,
+		UAParser:     uaparser,
+		ResultFormat: c.OutputFormat,
+		DebugFormat:  c.ErrorsFormat,
+		FailFast:     c.FailFast,
+
+		Workers:        c.Workers,
+		MaxOpenOutputs: c.MaxOpenOutputs,
+
+		SkipRename: c.SrcURI == stream,
+	}
+
+	switch {
+	case c.Columns != "":
+		pipeline.Columns, err = splitColumns(c.Columns)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		log.Debugw("using declared columns", "columns", pipeline.Columns)
+	case patterns.Columns() != nil:
+		pipeline.Columns = patterns.Columns()
+		log.Debugw("using columns declared by patterns file", "columns", pipeline.Columns)
+	}
+
+	pipeline.Tokenizer, err = c.newTokenizer(io, patterns)
+	if err != nil {
+		return nil, nil, nil, err
 	}
 
 	input, err := newInputOpenWalker(io, c.SrcURI)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
+	}
+	filter, err := c.newFilter(input)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	archives := &lazyio.ArchiveReader{Source: filter}
+	if c.Follow {
+		follower := &lazyio.Follower{
+			Source:   filter,
+			Interval: c.PollInterval,
+			Log:      log,
+		}
+		archives.Source = follower
+		pipeline.Retry = follower.Retry
+	}
+	pipeline.Source = archives
+	// globs are matched against the members of archives, not the archives
+	members := &lazyio.Filter{
+		Source:  archives,
+		Include: c.Include,
+		Exclude: c.Exclude,
+	}
+
+	if c.Compress != "" && c.OutputFormat == "parquet" {
+		return nil, nil, nil, fmt.Errorf("error: --compress can't be combined with --output-format=parquet")
 	}
-	pipeline.Source = input
 
 	output, err := newOutputOpener(io, c.DstURI)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
-	pipeline.Result = output
+	pipeline.Result = c.compress(output)
 
 	if c.ErrURI != "" {
 		opener, err := newOutputOpener(io, c.ErrURI)
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
+		}
+		pipeline.Debug = c.compress(opener)
+	}
+
+	if n := pipeline.TaskOutputs(); c.MaxOpenOutputs > 0 && c.MaxOpenOutputs < n {
+		return nil, nil, nil, fmt.Errorf("error: --max-open-outputs must be at least %d", n)
+	}
+
+	pipeline.Manifest, err = c.loadManifest(ctx, io, output)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return pipeline, members, archives, nil
+}
+
+func (c *TransformCmd) writeCoverage(p *pipeline.Pipeline) error {
+	f, err := os.Create(c.Coverage)
+	if err != nil {
+		return err
+	}
+	if err := p.Coverage.WriteReport(f, p.Patterns.IDs(), coverageTop); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// newFilter returns a filter of inputs by date, and that skips excluded
+// archives without extracting them.
+func (c *TransformCmd) newFilter(input inputOpenWalker) (*lazyio.Filter, error) {
+	filter := &lazyio.Filter{
+		Source:  input,
+		Exclude: c.Exclude,
+	}
+	for _, globs := range [][]string{c.Include, c.Exclude} {
+		for _, glob := range globs {
+			if err := lazyio.ValidateGlob(glob); err != nil {
+				return nil, fmt.Errorf("error: %w", err)
+			}
+		}
+	}
+
+	var err error
+	if filter.Since, err = parseTime(c.Since); err != nil {
+		return nil, err
+	}
+	if filter.Until, err = parseTime(c.Until); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+// parseTime parses a date, such as 2021-01-02, or an RFC 3339 timestamp.
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range []string{"2006-01-02", time.RFC3339} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("error: invalid time %q (expected YYYY-MM-DD or RFC 3339)", s)
+}
+
+func (c *TransformCmd) compress(opener lazyio.OutputOpener) lazyio.OutputOpener {
+	if c.Compress == "" {
+		return opener
+	}
+	return &lazyio.Compressor{
+		Opener: opener,
+		Format: c.Compress,
+	}
+}
+
+func splitColumns(s string) ([]string, error) {
+	columns := strings.Split(s, ",")
+	seen := make(map[string]bool, len(columns))
+	for i, column := range columns {
+		column = strings.TrimSpace(column)
+		switch {
+		case column == "":
+			return nil, fmt.Errorf("error: invalid column list %q", s)
+		case seen[column]:
+			return nil, fmt.Errorf("error: column repeated %q", column)
+		}
+		seen[column] = true
+		columns[i] = column
+	}
+	return columns, nil
+}
+
+func (c *TransformCmd) newTokenizer(io *sys.IO, patterns *patterns.Patterns) (tokenizer.Tokenizer, error) {
+	log := io.Log
+	switch {
+	case c.LogFormat != "" && c.Format != "nginx":
+		return nil, fmt.Errorf("error: a custom log format requires --format=nginx")
+	case c.LogFormat != "":
+		log.Debugw("creating nginx tokenizer", "format", c.LogFormat)
+		return tokenizer.NewNginx(c.LogFormat)
+	case len(c.JSONFields) > 0 && c.Format != "json":
+		return nil, fmt.Errorf("error: JSON field mappings require --format=json")
+	case len(c.JSONFields) > 0:
+		log.Debugw("creating JSON tokenizer", "fields", c.JSONFields)
+		return &tokenizer.JSON{Fields: c.JSONFields}, nil
+	case c.Format == "auto":
+		log.Debugw("detecting log format of each input")
+		return nil, nil
+	case c.Format == "" && patterns.Tokenizer() != nil:
+		log.Debugw("using tokenizer declared by patterns file")
+		return patterns.Tokenizer(), nil
+	case c.Format == "":
+		return tokenizer.ALB, nil
+	}
+
+	tokenizer, ok := tokenizer.Lookup(c.Format)
+	if !ok {
+		return nil, fmt.Errorf("error: unknown log format %q", c.Format)
+	}
+	return tokenizer, nil
+}
+
+// loadManifest returns the manifest in DST when resuming, the local state
+// file if set, or nil.
+func (c *TransformCmd) loadManifest(ctx context.Context, io *sys.IO, output lazyio.OutputOpener) (*pipeline.Manifest, error) {
+	switch {
+	case c.Resume:
+		return loadManifest(ctx, io, c.DstURI, pipeline.ManifestName, output)
+	case c.State != "":
+		dir := filepath.Dir(c.State)
+		return loadManifest(ctx, io, dir, filepath.Base(c.State), &lazyio.FileWriter{Dir: dir})
+	}
+	return nil, nil
+}
+
+func loadManifest(ctx context.Context, io *sys.IO, uri, name string, output lazyio.OutputOpener) (*pipeline.Manifest, error) {
+	log := io.Log
+	manifest := &pipeline.Manifest{
+		Path:     name,
+		Opener:   output,
+		Interval: time.Minute,
+	}
+
+	var reader lazyio.InputOpener
+	switch {
+	case strings.HasPrefix(uri, "s3://") || strings.HasPrefix(uri, "S3://"):
+		log.Debugw("loading manifest from S3", "uri", uri, "name", name)
+		s3reader, err := lazyio.NewS3Reader(io, uri)
+		if err != nil {
+			return nil, err
 		}
-		pipeline.Debug = opener
+		reader = s3reader
+	default:
+		log.Debugw("loading manifest from FS", "uri", uri, "name", name)
+		reader = &lazyio.FileReader{Dir: filepath.Clean(uri)}
+	}
+
+	r, err := reader.Open(ctx, name)
+	var aerr awserr.Error
+	switch {
+	case os.IsNotExist(err):
+		return manifest, nil
+	case errors.As(err, &aerr) && aerr.Code() == s3.ErrCodeNoSuchKey:
+		return manifest, nil
+	case err != nil:
+		return nil, err
 	}
+	defer r.Close()
 
-	return pipeline, input, nil
+	if err := manifest.Load(r); err != nil {
+		return nil, fmt.Errorf("error: invalid manifest: %w", err)
+	}
+	return manifest, nil
 }
 
-func loadPatterns(io *sys.IO, uri string) (*patterns.Patterns, error) {
+func loadPatterns(ctx context.Context, io *sys.IO, uri string) (*patterns.Patterns, error) {
 	log := io.Log
 	switch {
 	case strings.HasPrefix(uri, "s3://") || strings.HasPrefix(uri, "S3://"):
@@ -104,7 +445,7 @@ func loadPatterns(io *sys.IO, uri string) (*patterns.Patterns, error) {
 		if err != nil {
 			return nil, err
 		}
-		result, err := downloader.GetObject(&s3.GetObjectInput{
+		result, err := downloader.GetObjectWithContext(ctx, &s3.GetObjectInput{
 			Bucket: aws.String(parsed.Bucket),
 			Key:    aws.String(parsed.Key),
 		})
@@ -130,6 +471,9 @@ type inputOpenWalker interface {
 func newInputOpenWalker(io *sys.IO, uri string) (inputOpenWalker, error) {
 	log := io.Log
 	switch {
+	case uri == stream:
+		log.Debugw("creating stdin input walker")
+		return &lazyio.StreamReader{R: io.Stdin}, nil
 	case strings.HasPrefix(uri, "s3://") || strings.HasPrefix(uri, "S3://"):
 		log.Debugw("creating S3 input walker", "uri", uri)
 		return lazyio.NewS3Reader(io, uri)
@@ -148,6 +492,9 @@ func newInputOpenWalker(io *sys.IO, uri string) (inputOpenWalker, error) {
 func newOutputOpener(io *sys.IO, uri string) (lazyio.OutputOpener, error) {
 	log := io.Log
 	switch {
+	case uri == stream:
+		log.Debugw("creating stdout output writer")
+		return &lazyio.StreamWriter{W: io.Stdout}, nil
 	case strings.HasPrefix(uri, "s3://") || strings.HasPrefix(uri, "S3://"):
 		log.Debugw("creating S3 output writer", "uri", uri)
 		return lazyio.NewS3Writer(io, uri)
@@ -0,0 +1,296 @@
+package lazyio
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	pathlib "path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var _ InputOpener = &ArchiveReader{}
+var _ InputWalker = &ArchiveReader{}
+var _ Releaser = &ArchiveReader{}
+
+// ArchiveSep separates the path of an archive from the path of a member.
+const ArchiveSep = "!/"
+
+var tarExts = []string{
+	".tar", ".tar.bz2", ".tar.gz", ".tar.xz", ".tar.zst",
+	".tbz2", ".tgz", ".txz",
+}
+
+// An ArchiveReader walks the members of tar and zip archives as if they were
+// regular inputs, with paths such as "bundle.tar.gz!/2021/01/a.log". Each
+// archive is extracted to a temporary directory, which is removed once all of
+// its members are released, or by Close.
+type ArchiveReader struct {
+	Source interface {
+		InputOpener
+		InputWalker
+	}
+
+	mu      sync.Mutex
+	dir     string
+	members map[string]*extraction
+}
+
+// An extraction is the directory an archive was extracted to, and the number
+// of its members that haven't been released.
+type extraction struct {
+	dir     string
+	pending int
+}
+
+func (r *ArchiveReader) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.members = nil
+	if r.dir == "" {
+		return nil
+	}
+	err := os.RemoveAll(r.dir)
+	r.dir = ""
+	return err
+}
+
+func (r *ArchiveReader) Open(ctx context.Context, path string) (io.ReadCloser, error) {
+	idx := strings.Index(path, ArchiveSep)
+	if idx < 0 {
+		return r.Source.Open(ctx, path)
+	}
+
+	r.mu.Lock()
+	e := r.members[path]
+	r.mu.Unlock()
+	if e == nil {
+		// never extracted, already released, or not a member
+		return nil, &os.PathError{Op: "open", Path: path, Err: os.ErrNotExist}
+	}
+	return os.Open(e.path(path))
+}
+
+// Release removes the extracted member at path, and the directory of its
+// archive once every member has been released.
+func (r *ArchiveReader) Release(path string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e := r.members[path]
+	if e == nil {
+		return nil
+	}
+	delete(r.members, path)
+	e.pending--
+	if e.pending < 1 {
+		return os.RemoveAll(e.dir)
+	}
+	return os.Remove(e.path(path))
+}
+
+func (e *extraction) path(path string) string {
+	member := path[strings.Index(path, ArchiveSep)+len(ArchiveSep):]
+	return filepath.Join(e.dir, filepath.FromSlash(member))
+}
+
+// Walk walks the inputs of Source, replacing each archive with its members.
+// Members have the same version and modification time as their archive. An
+// archive that can't be extracted is walked with Err set.
+func (r *ArchiveReader) Walk(ctx context.Context, fn func(Entry) error) error {
+	return r.Source.Walk(ctx, func(entry Entry) error {
+		path := entry.Path
+		var extract func(context.Context, string, string) ([]string, error)
+		switch {
+		case isZip(path):
+			extract = r.extractZip
+		case isTar(path):
+			extract = r.extractTar
+		default:
+			return fn(entry)
+		}
+		members, err := r.extract(ctx, path, extract)
+		if err != nil {
+			entry.Err = fmt.Errorf("unable to extract %q: %w", path, err)
+			return fn(entry)
+		}
+		for _, member := range members {
+			err := fn(Entry{
+				Path:     path + ArchiveSep + member,
+				Version:  entry.Version,
+				Modified: entry.Modified,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// extract extracts the archive at path to a new directory, and returns the
+// paths of its members.
+func (r *ArchiveReader) extract(
+	ctx context.Context, path string,
+	extract func(context.Context, string, string) ([]string, error),
+) ([]string, error) {
+	r.mu.Lock()
+	if r.dir == "" {
+		dir, err := ioutil.TempDir("", "carpenter-")
+		if err != nil {
+			r.mu.Unlock()
+			return nil, err
+		}
+		r.dir = dir
+		r.members = make(map[string]*extraction)
+	}
+	dir, err := ioutil.TempDir(r.dir, "archive-")
+	r.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := extract(ctx, path, dir)
+	if err != nil || len(members) < 1 {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e := &extraction{dir: dir}
+	for _, member := range members {
+		path := path + ArchiveSep + member
+		switch old := r.members[path]; {
+		case old == e:
+			continue
+		case old != nil:
+			// the member of an earlier version of the archive is replaced
+			if old.pending--; old.pending < 1 {
+				os.RemoveAll(old.dir)
+			}
+		}
+		e.pending++
+		r.members[path] = e
+	}
+	return members, nil
+}
+
+func (r *ArchiveReader) extractTar(ctx context.Context, path, dir string) ([]string, error) {
+	input := &Input{Path: path, Opener: r.Source}
+	defer input.Close()
+	src, err := input.Open(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var members []string
+	tr := tar.NewReader(src)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		member, ok := cleanMember(hdr.Name)
+		if !ok {
+			continue
+		}
+		if err := extractMember(dir, member, tr); err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	return members, nil
+}
+
+func (r *ArchiveReader) extractZip(ctx context.Context, path, dir string) ([]string, error) {
+	src, err := r.Source.Open(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	// zip requires random access, so the archive is downloaded first.
+	tmp, err := ioutil.TempFile("", "carpenter-*.zip")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	size, err := io.Copy(tmp, src)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := zip.NewReader(tmp, size)
+	if err != nil {
+		return nil, err
+	}
+
+	var members []string
+	for _, f := range zr.File {
+		if !f.Mode().IsRegular() {
+			continue
+		}
+		member, ok := cleanMember(f.Name)
+		if !ok {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		err = extractMember(dir, member, rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	return members, nil
+}
+
+func extractMember(dir, member string, src io.Reader) error {
+	dst := filepath.Join(dir, filepath.FromSlash(member))
+	if err := os.MkdirAll(filepath.Dir(dst), 0777); err != nil {
+		return err
+	}
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, src); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// cleanMember returns the path of an archive member relative to the root of
+// the archive, or false if the member has no name.
+func cleanMember(name string) (string, bool) {
+	member := pathlib.Clean("/" + name)[1:]
+	return member, member != ""
+}
+
+func isTar(path string) bool {
+	for _, ext := range tarExts {
+		if strings.HasSuffix(path, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+func isZip(path string) bool {
+	return strings.HasSuffix(path, ".zip")
+}
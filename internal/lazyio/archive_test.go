@@ -0,0 +1,138 @@
+package lazyio_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/sjansen/carpenter/internal/lazyio"
+)
+
+func TestArchiveReader(t *testing.T) {
+	require := require.New(t)
+
+	r := &lazyio.ArchiveReader{
+		Source: &lazyio.FileReader{Dir: "testdata/archives"},
+	}
+	defer r.Close()
+
+	var paths []string
+	err := r.Walk(context.Background(), func(entry lazyio.Entry) error {
+		require.NotEmpty(entry.Version, entry.Path)
+		paths = append(paths, entry.Path)
+		return nil
+	})
+	require.NoError(err)
+
+	sort.Strings(paths)
+	require.Equal([]string{
+		"bundle.tar.gz!/2021/01/a.log",
+		"bundle.tar.gz!/2021/01/b.log.gz",
+		"bundle.zip!/2021/01/a.log",
+		"bundle.zip!/2021/01/b.log.gz",
+		"plain.log",
+	}, paths)
+
+	for _, path := range paths {
+		input := &lazyio.Input{
+			Path:   path,
+			Opener: r,
+		}
+		src, err := input.Open(context.Background())
+		require.NoError(err, path)
+		actual, err := ioutil.ReadAll(src)
+		require.NoError(err, path)
+		require.Equal("Spoon!\n", string(actual), path)
+		require.NoError(input.Close(), path)
+	}
+
+	input := &lazyio.Input{Path: "bundle.zip!/2021/01/b.log.gz"}
+	require.Equal("bundle.zip!/2021/01/b", input.StripExt())
+
+	_, err = r.Open(context.Background(), "bundle.zip!/missing.log")
+	require.True(os.IsNotExist(err))
+
+	require.NoError(r.Release("bundle.zip!/2021/01/a.log"))
+	_, err = r.Open(context.Background(), "bundle.zip!/2021/01/a.log")
+	require.True(os.IsNotExist(err))
+	src, err := r.Open(context.Background(), "bundle.zip!/2021/01/b.log.gz")
+	require.NoError(err)
+	require.NoError(src.Close())
+
+	require.NoError(r.Close())
+	_, err = r.Open(context.Background(), "bundle.tar.gz!/2021/01/a.log")
+	require.Error(err)
+}
+
+func TestArchiveReaderRelease(t *testing.T) {
+	require := require.New(t)
+
+	tmp, err := ioutil.TempDir("", "carpenter-")
+	require.NoError(err)
+	defer os.RemoveAll(tmp)
+	defer os.Setenv("TMPDIR", os.Getenv("TMPDIR"))
+	os.Setenv("TMPDIR", tmp)
+
+	r := &lazyio.ArchiveReader{
+		Source: &lazyio.FileReader{Dir: "testdata/archives"},
+	}
+	defer r.Close()
+
+	var paths []string
+	err = r.Walk(context.Background(), func(entry lazyio.Entry) error {
+		paths = append(paths, entry.Path)
+		return nil
+	})
+	require.NoError(err)
+
+	for _, path := range paths {
+		require.NoError(r.Release(path), path)
+	}
+	files := 0
+	err = filepath.Walk(tmp, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			files++
+		}
+		return err
+	})
+	require.NoError(err)
+	require.Zero(files)
+}
+
+func TestArchiveReaderErrors(t *testing.T) {
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "carpenter-")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+	for name, content := range map[string]string{
+		"broken.tar.gz": "Spoon!\n",
+		"broken.zip":    "Spoon!\n",
+		"plain.log":     "Spoon!\n",
+	} {
+		err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0666)
+		require.NoError(err)
+	}
+
+	r := &lazyio.ArchiveReader{
+		Source: &lazyio.FileReader{Dir: dir},
+	}
+	defer r.Close()
+
+	failed := map[string]bool{}
+	err = r.Walk(context.Background(), func(entry lazyio.Entry) error {
+		failed[entry.Path] = entry.Err != nil
+		return nil
+	})
+	require.NoError(err)
+	require.Equal(map[string]bool{
+		"broken.tar.gz": true,
+		"broken.zip":    true,
+		"plain.log":     false,
+	}, failed)
+}
@@ -2,6 +2,7 @@ package lazyio
 
 import (
 	"bytes"
+	"context"
 	"io"
 )
 
@@ -21,7 +22,7 @@ type BufferWriter struct {
 	buffers map[string]*buffer
 }
 
-func (b *BufferWriter) Open(path string) (io.WriteCloser, error) {
+func (b *BufferWriter) Open(ctx context.Context, path string) (io.WriteCloser, error) {
 	if b.buffers == nil {
 		b.buffers = make(map[string]*buffer, 1)
 	}
@@ -1,6 +1,7 @@
 package lazyio_test
 
 import (
+	"context"
 	"testing"
 
 	"github.com/sjansen/carpenter/internal/lazyio"
@@ -12,7 +13,7 @@ func TestBufferOpener(t *testing.T) {
 
 	o := lazyio.BufferWriter{}
 
-	w, err := o.Open("foo")
+	w, err := o.Open(context.Background(), "foo")
 	require.NoError(err, "open")
 
 	require.Equal([]string{"foo"}, o.Buffers())
@@ -0,0 +1,84 @@
+package lazyio
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+var _ OutputOpener = &Compressor{}
+
+// Compressions are the values accepted by Compressor.Format.
+var Compressions = []string{"gzip", "zstd"}
+
+// A Compressor compresses everything written to the outputs it opens.
+type Compressor struct {
+	Opener OutputOpener
+	// Format is "gzip" or "zstd".
+	Format string
+}
+
+// Ext returns the extension that should be appended to compressed paths.
+func (c *Compressor) Ext() string {
+	switch c.Format {
+	case "gzip":
+		return ".gz"
+	case "zstd":
+		return ".zst"
+	}
+	return ""
+}
+
+func (c *Compressor) Open(ctx context.Context, path string) (io.WriteCloser, error) {
+	w, err := c.Opener.Open(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cw io.WriteCloser
+	switch c.Format {
+	case "gzip":
+		cw = gzip.NewWriter(w)
+	case "zstd":
+		cw, err = zstd.NewWriter(w)
+	default:
+		err = fmt.Errorf("unsupported compression: %q", c.Format)
+	}
+	if err != nil {
+		Abort(w)
+		return nil, err
+	}
+
+	return &compressedWriter{WriteCloser: cw, w: w}, nil
+}
+
+// OutputExt returns the extension appended to paths by opener, if any.
+func OutputExt(opener OutputOpener) string {
+	if c, ok := opener.(*Compressor); ok {
+		return c.Ext()
+	}
+	return ""
+}
+
+var _ Aborter = &compressedWriter{}
+
+type compressedWriter struct {
+	io.WriteCloser
+	w io.WriteCloser
+}
+
+func (cw *compressedWriter) Abort() error {
+	cw.WriteCloser.Close()
+	return Abort(cw.w)
+}
+
+func (cw *compressedWriter) Close() error {
+	if err := cw.WriteCloser.Close(); err != nil {
+		Abort(cw.w)
+		return err
+	}
+	return cw.w.Close()
+}
@@ -0,0 +1,110 @@
+package lazyio_test
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sjansen/carpenter/internal/lazyio"
+)
+
+func TestCompressor(t *testing.T) {
+	require := require.New(t)
+
+	o := &lazyio.BufferWriter{}
+	require.Equal("", lazyio.OutputExt(o))
+
+	for _, format := range lazyio.Compressions {
+		c := &lazyio.Compressor{
+			Opener: o,
+			Format: format,
+		}
+		path := "space/magic.txt" + lazyio.OutputExt(c)
+
+		w, err := c.Open(context.Background(), path)
+		require.NoError(err, format)
+		_, err = w.Write([]byte("Spoon!\n"))
+		require.NoError(err, format)
+		err = w.Close()
+		require.NoError(err, format)
+
+		buf := o.Buffer(path)
+		require.NotNil(buf, format)
+
+		var actual []byte
+		switch format {
+		case "gzip":
+			require.Equal(".gz", c.Ext())
+			r, err := gzip.NewReader(buf)
+			require.NoError(err, format)
+			actual, err = ioutil.ReadAll(r)
+			require.NoError(err, format)
+		case "zstd":
+			require.Equal(".zst", c.Ext())
+			r, err := zstd.NewReader(buf)
+			require.NoError(err, format)
+			actual, err = ioutil.ReadAll(r)
+			require.NoError(err, format)
+			r.Close()
+		}
+		require.Equal("Spoon!\n", string(actual), format)
+	}
+
+	c := &lazyio.Compressor{
+		Opener: o,
+		Format: "lzma",
+	}
+	_, err := c.Open(context.Background(), "space/magic.txt.lzma")
+	require.Error(err)
+}
+
+func TestCompressorAborts(t *testing.T) {
+	require := require.New(t)
+
+	o := &abortingOpener{}
+	c := &lazyio.Compressor{
+		Opener: o,
+		Format: "lzma",
+	}
+	_, err := c.Open(context.Background(), "space/magic.txt.lzma")
+	require.Error(err)
+	require.Equal([]string{"abort"}, o.calls)
+
+	o.calls = nil
+	c.Format = "gzip"
+	w, err := c.Open(context.Background(), "space/magic.txt.gz")
+	require.NoError(err)
+	_, err = w.Write([]byte("Spoon!\n"))
+	require.Error(err)
+	require.Error(w.Close())
+	require.Equal([]string{"write", "abort"}, o.calls)
+}
+
+type abortingOpener struct {
+	calls []string
+}
+
+func (o *abortingOpener) Open(ctx context.Context, path string) (io.WriteCloser, error) {
+	return o, nil
+}
+
+func (o *abortingOpener) Write(p []byte) (int, error) {
+	o.calls = append(o.calls, "write")
+	return 0, errors.New("disk full")
+}
+
+func (o *abortingOpener) Abort() error {
+	o.calls = append(o.calls, "abort")
+	return nil
+}
+
+func (o *abortingOpener) Close() error {
+	o.calls = append(o.calls, "close")
+	return nil
+}
@@ -1,34 +1,63 @@
 package lazyio
 
 import (
+	"context"
 	"encoding/csv"
 	"io"
 )
 
+var _ RowWriter = &CSV{}
+var _ Table = &CSV{}
+
 type CSV struct {
 	Path   string
 	Opener OutputOpener
+	// Context is used to open the output, if set.
+	Context context.Context
 
-	w   io.WriteCloser
-	csv *csv.Writer
-	err error
+	w    io.WriteCloser
+	csv  *csv.Writer
+	cols []string
+	row  []string
+	err  error
 }
 
-func (f *CSV) Close() error {
+func (f *CSV) Abort() error {
 	if f == nil || f.w == nil {
 		return nil
 	}
+	return Abort(f.w)
+}
+
+// Close commits the output, or returns the error that stopped it from being
+// opened or written.
+func (f *CSV) Close() error {
+	switch {
+	case f == nil:
+		return nil
+	case f.err != nil:
+		f.Abort()
+		return f.err
+	case f.w == nil:
+		return nil
+	}
 	if f.csv != nil {
 		f.csv.Flush()
+		if err := f.csv.Error(); err != nil {
+			Abort(f.w)
+			return err
+		}
 	}
 	return f.w.Close()
 }
 
 func (f *CSV) Error() error {
-	if f == nil || f.w == nil {
+	switch {
+	case f == nil:
 		return nil
-	}
-	if f.csv != nil {
+	case f.err != nil:
+		return f.err
+	case f.csv != nil:
 		return f.csv.Error()
 	}
 	return nil
@@ -47,7 +76,7 @@ func (f *CSV) Write(row ...string) error {
 	case f.err != nil:
 		return f.err
 	case f.w == nil:
-		w, err := f.Opener.Open(f.Path)
+		w, err := f.Opener.Open(background(f.Context), f.Path)
 		if err != nil {
 			f.err = err
 			return err
@@ -58,3 +87,24 @@ func (f *CSV) Write(row ...string) error {
 
 	return f.csv.Write(row)
 }
+
+func (f *CSV) WriteHeader(cols ...string) error {
+	if f == nil {
+		return nil
+	}
+	f.cols = cols
+	f.row = make([]string, len(cols))
+	return f.Write(cols...)
+}
+
+// WriteRecord writes the values of the header's columns, using an empty
+// string for missing values.
+func (f *CSV) WriteRecord(record map[string]string) error {
+	if f == nil {
+		return nil
+	}
+	for i, k := range f.cols {
+		f.row[i] = record[k]
+	}
+	return f.Write(f.row...)
+}
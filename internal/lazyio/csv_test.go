@@ -32,3 +32,24 @@ func TestCSV(t *testing.T) {
 	buf := o.Buffer(expectedPath)
 	require.Equal("a,b,c\n", buf.String())
 }
+
+func TestCSVRecords(t *testing.T) {
+	require := require.New(t)
+
+	o := &lazyio.BufferWriter{}
+	expectedPath := "space/magic.csv"
+	csv := &lazyio.CSV{
+		Path:   expectedPath,
+		Opener: o,
+	}
+
+	err := csv.WriteHeader("a", "b", "c")
+	require.NoError(err)
+	err = csv.WriteRecord(map[string]string{"a": "1", "c": "3", "d": "4"})
+	require.NoError(err)
+
+	err = csv.Close()
+	require.NoError(err)
+	buf := o.Buffer(expectedPath)
+	require.Equal("a,b,c\n1,,3\n", buf.String())
+}
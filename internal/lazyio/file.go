@@ -1,9 +1,13 @@
 package lazyio
 
 import (
+	"context"
 	"io"
 	"os"
 	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 var _ InputOpener = &FileReader{}
@@ -14,20 +18,25 @@ type FileReader struct {
 	Dir string
 }
 
-func (fr *FileReader) Open(path string) (io.ReadCloser, error) {
+func (fr *FileReader) Open(ctx context.Context, path string) (io.ReadCloser, error) {
 	path = filepath.Join(fr.Dir, filepath.FromSlash(path))
 	return os.Open(path)
 }
 
-func (fr *FileReader) Walk(fn func(string) error) error {
+func (fr *FileReader) Walk(ctx context.Context, fn func(Entry) error) error {
 	return filepath.Walk(fr.Dir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
+		} else if err := ctx.Err(); err != nil {
+			return err
 		} else if !info.Mode().IsRegular() {
 			return nil
 		}
-		suffix := fr.stripPrefix(path)
-		return fn(suffix)
+		return fn(Entry{
+			Path:     fr.stripPrefix(path),
+			Version:  info.ModTime().UTC().Format(time.RFC3339Nano),
+			Modified: info.ModTime(),
+		})
 	})
 }
 
@@ -41,15 +50,45 @@ func (fr *FileReader) stripPrefix(path string) string {
 	return path
 }
 
+// A FileWriter writes outputs to temporary files that replace the actual
+// files when closed, so files are never partially written.
 type FileWriter struct {
 	Dir string
 }
 
-func (fw *FileWriter) Open(path string) (io.WriteCloser, error) {
+func (fw *FileWriter) Open(ctx context.Context, path string) (io.WriteCloser, error) {
 	path = filepath.Join(fw.Dir, filepath.FromSlash(path))
 	err := os.MkdirAll(filepath.Dir(path), 0777)
 	if err != nil {
 		return nil, err
 	}
-	return os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0666)
+	tmp := filepath.Join(
+		filepath.Dir(path),
+		"."+filepath.Base(path)+"."+uuid.New().String()+".tmp",
+	)
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0666)
+	if err != nil {
+		return nil, err
+	}
+	return &tempFile{File: f, path: path}, nil
+}
+
+var _ Aborter = &tempFile{}
+
+type tempFile struct {
+	*os.File
+	path string
+}
+
+func (f *tempFile) Abort() error {
+	f.File.Close()
+	return os.Remove(f.Name())
+}
+
+func (f *tempFile) Close() error {
+	if err := f.File.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), f.path)
 }
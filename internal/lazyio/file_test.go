@@ -0,0 +1,57 @@
+package lazyio_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/sjansen/carpenter/internal/lazyio"
+)
+
+func TestFileWriter(t *testing.T) {
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "carpenter-")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	fw := &lazyio.FileWriter{Dir: dir}
+	path := filepath.Join(dir, "space", "magic.txt")
+
+	for _, content := range []string{"Spoon! Spoon!\n", "Spoon!\n"} {
+		w, err := fw.Open(ctx, "space/magic.txt")
+		require.NoError(err)
+		_, err = w.Write([]byte(content))
+		require.NoError(err)
+		require.NoError(w.Close())
+
+		actual, err := ioutil.ReadFile(path)
+		require.NoError(err)
+		require.Equal(content, string(actual))
+	}
+
+	w, err := fw.Open(ctx, "space/magic.txt")
+	require.NoError(err)
+	_, err = w.Write([]byte("Not in the face!\n"))
+	require.NoError(err)
+
+	actual, err := ioutil.ReadFile(path)
+	require.NoError(err)
+	require.Equal("Spoon!\n", string(actual))
+
+	require.Implements((*lazyio.Aborter)(nil), w)
+	require.NoError(w.(lazyio.Aborter).Abort())
+
+	actual, err = ioutil.ReadFile(path)
+	require.NoError(err)
+	require.Equal("Spoon!\n", string(actual))
+
+	files, err := ioutil.ReadDir(filepath.Join(dir, "space"))
+	require.NoError(err)
+	require.Len(files, 1)
+}
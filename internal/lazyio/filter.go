@@ -0,0 +1,143 @@
+package lazyio
+
+import (
+	"context"
+	"fmt"
+	"io"
+	pathlib "path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var _ InputOpener = &Filter{}
+var _ InputWalker = &Filter{}
+
+// keyDates match dates in paths such as "AWSLogs/.../2021/01/02/..." or
+// "example.2021-01-02-03.gz".
+var keyDates = []*regexp.Regexp{
+	regexp.MustCompile(`(?:^|/)(\d{4})/(\d{2})/(\d{2})/`),
+	regexp.MustCompile(`(\d{4})-(\d{2})-(\d{2})`),
+	regexp.MustCompile(`_(\d{4})(\d{2})(\d{2})T\d{4}Z_`),
+}
+
+// A Filter walks the inputs of Source that match its globs and time range.
+type Filter struct {
+	Source interface {
+		InputOpener
+		InputWalker
+	}
+
+	// Include, if not empty, skips inputs that don't match any of its globs.
+	Include []string
+	// Exclude skips inputs that match any of its globs.
+	Exclude []string
+
+	// Since and Until, if not zero, skip inputs outside [Since, Until). The
+	// date in an input's path is used if found, and otherwise the time it
+	// was modified.
+	Since time.Time
+	Until time.Time
+}
+
+// ValidateGlob returns an error if glob is malformed. Globs are matched
+// against slash-separated paths. A "**" segment matches zero or more
+// directories, and other segments use the syntax of path.Match.
+func ValidateGlob(glob string) error {
+	if _, err := pathlib.Match(glob, ""); err != nil {
+		return fmt.Errorf("invalid glob: %q", glob)
+	}
+	return nil
+}
+
+func (f *Filter) Open(ctx context.Context, path string) (io.ReadCloser, error) {
+	return f.Source.Open(ctx, path)
+}
+
+// Walk walks the inputs that match, releasing the others if Source is a
+// Releaser.
+func (f *Filter) Walk(ctx context.Context, fn func(Entry) error) error {
+	releaser, _ := f.Source.(Releaser)
+	return f.Source.Walk(ctx, func(entry Entry) error {
+		if f.Match(entry) {
+			return fn(entry)
+		} else if releaser != nil {
+			return releaser.Release(entry.Path)
+		}
+		return nil
+	})
+}
+
+// Match returns true if the input should be walked.
+func (f *Filter) Match(entry Entry) bool {
+	if len(f.Include) > 0 && !matchAny(f.Include, entry.Path) {
+		return false
+	}
+	if matchAny(f.Exclude, entry.Path) {
+		return false
+	}
+	if f.Since.IsZero() && f.Until.IsZero() {
+		return true
+	}
+
+	start, end := entry.Modified, entry.Modified
+	if day, ok := keyDate(entry.Path); ok {
+		start, end = day, day.AddDate(0, 0, 1)
+	} else if entry.Modified.IsZero() {
+		return false
+	} else {
+		end = end.Add(time.Nanosecond)
+	}
+	switch {
+	case !f.Since.IsZero() && !end.After(f.Since):
+		return false
+	case !f.Until.IsZero() && !start.Before(f.Until):
+		return false
+	}
+	return true
+}
+
+// keyDate returns the first UTC day found in path.
+func keyDate(path string) (time.Time, bool) {
+	for _, re := range keyDates {
+		m := re.FindStringSubmatch(path)
+		if m == nil {
+			continue
+		}
+		day, err := time.Parse("2006-01-02", m[1]+"-"+m[2]+"-"+m[3])
+		if err == nil {
+			return day, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func matchAny(globs []string, path string) bool {
+	for _, glob := range globs {
+		if matchGlob(strings.Split(glob, "/"), strings.Split(path, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchGlob(glob, path []string) bool {
+	for len(glob) > 0 {
+		if glob[0] == "**" {
+			for i := 0; i <= len(path); i++ {
+				if matchGlob(glob[1:], path[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(path) < 1 {
+			return false
+		}
+		if ok, _ := pathlib.Match(glob[0], path[0]); !ok {
+			return false
+		}
+		glob, path = glob[1:], path[1:]
+	}
+	return len(path) < 1
+}
@@ -0,0 +1,163 @@
+package lazyio_test
+
+import (
+	"context"
+	"os"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/sjansen/carpenter/internal/lazyio"
+)
+
+func TestFilter(t *testing.T) {
+	require := require.New(t)
+
+	const alb = "AWSLogs/123456789012/elasticloadbalancing/us-east-2/2021/01/02/" +
+		"123456789012_elasticloadbalancing_us-east-2_app.my-lb.1a2b3c_20210102T2355Z_192.0.2.1_abc.log.gz"
+	const cloudfront = "cf/E2K2LNL5N3WR51.2021-01-03-04.a1b2c3d4.gz"
+	modified := time.Date(2021, 1, 4, 12, 0, 0, 0, time.UTC)
+
+	day := func(s string) time.Time {
+		t, err := time.Parse("2006-01-02", s)
+		require.NoError(err)
+		return t
+	}
+
+	for _, tc := range []struct {
+		filter   lazyio.Filter
+		expected []string
+	}{{
+		filter:   lazyio.Filter{},
+		expected: []string{alb, cloudfront, "example.log"},
+	}, {
+		filter: lazyio.Filter{
+			Include: []string{"AWSLogs/*/elasticloadbalancing/us-east-2/**/*.log.gz"},
+		},
+		expected: []string{alb},
+	}, {
+		filter: lazyio.Filter{
+			Include: []string{"**/*.gz"},
+			Exclude: []string{"AWSLogs/**"},
+		},
+		expected: []string{cloudfront},
+	}, {
+		filter: lazyio.Filter{
+			Include: []string{"**"},
+			Exclude: []string{"*.log"},
+		},
+		expected: []string{alb, cloudfront},
+	}, {
+		filter: lazyio.Filter{
+			Since: day("2021-01-02"),
+			Until: day("2021-01-03"),
+		},
+		expected: []string{alb},
+	}, {
+		filter: lazyio.Filter{
+			Since: day("2021-01-03"),
+		},
+		expected: []string{cloudfront, "example.log"},
+	}, {
+		filter: lazyio.Filter{
+			Since: time.Date(2021, 1, 2, 23, 0, 0, 0, time.UTC),
+			Until: modified,
+		},
+		expected: []string{alb, cloudfront},
+	}, {
+		filter: lazyio.Filter{
+			Until: modified.Add(time.Second),
+		},
+		expected: []string{alb, cloudfront, "example.log"},
+	}} {
+		var actual []string
+		for _, path := range []string{alb, cloudfront, "example.log"} {
+			entry := lazyio.Entry{Path: path, Modified: modified}
+			if tc.filter.Match(entry) {
+				actual = append(actual, path)
+			}
+		}
+		require.Equal(tc.expected, actual, "%+v", tc.filter)
+	}
+
+	require.NoError(lazyio.ValidateGlob("**/2021/*/*.log"))
+	require.Error(lazyio.ValidateGlob("[2021"))
+}
+
+func TestFilterArchives(t *testing.T) {
+	require := require.New(t)
+
+	for _, tc := range []struct {
+		include  []string
+		exclude  []string
+		expected []string
+	}{{
+		include: []string{"**/*.log"},
+		expected: []string{
+			"bundle.tar.gz!/2021/01/a.log",
+			"bundle.zip!/2021/01/a.log",
+			"plain.log",
+		},
+	}, {
+		include: []string{"*.zip!/**"},
+		expected: []string{
+			"bundle.zip!/2021/01/a.log",
+			"bundle.zip!/2021/01/b.log.gz",
+		},
+	}, {
+		exclude: []string{"*.zip", "**/b.log.gz"},
+		expected: []string{
+			"bundle.tar.gz!/2021/01/a.log",
+			"plain.log",
+		},
+	}} {
+		archives := &lazyio.ArchiveReader{
+			Source: &lazyio.Filter{
+				Source:  &lazyio.FileReader{Dir: "testdata/archives"},
+				Exclude: tc.exclude,
+			},
+		}
+		members := &lazyio.Filter{
+			Source:  archives,
+			Include: tc.include,
+			Exclude: tc.exclude,
+		}
+
+		var actual []string
+		err := members.Walk(context.Background(), func(entry lazyio.Entry) error {
+			actual = append(actual, entry.Path)
+			return nil
+		})
+		require.NoError(err)
+
+		sort.Strings(actual)
+		require.Equal(tc.expected, actual)
+		for _, path := range skipped(tc.expected) {
+			_, err := archives.Open(context.Background(), path)
+			require.True(os.IsNotExist(err), path)
+		}
+		require.NoError(archives.Close())
+	}
+}
+
+// skipped returns the archive members in testdata that aren't in walked.
+func skipped(walked []string) []string {
+	var result []string
+	for _, path := range []string{
+		"bundle.tar.gz!/2021/01/a.log",
+		"bundle.tar.gz!/2021/01/b.log.gz",
+		"bundle.zip!/2021/01/a.log",
+		"bundle.zip!/2021/01/b.log.gz",
+	} {
+		found := false
+		for _, w := range walked {
+			found = found || w == path
+		}
+		if !found {
+			result = append(result, path)
+		}
+	}
+	return result
+}
@@ -0,0 +1,94 @@
+package lazyio
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sjansen/carpenter/internal/sys"
+)
+
+var _ InputOpener = &Follower{}
+var _ InputWalker = &Follower{}
+
+// A Follower walks Source repeatedly, waiting Interval between walks, so new
+// inputs are walked as they appear. An input is walked again only if its
+// version changes, or if it is retried.
+type Follower struct {
+	Source interface {
+		InputOpener
+		InputWalker
+	}
+	Interval time.Duration
+	// Log, if set, logs walks of Source that fail. They are retried after
+	// Interval.
+	Log sys.Logger
+
+	mu   sync.Mutex
+	seen map[string]string
+}
+
+func (f *Follower) Open(ctx context.Context, path string) (io.ReadCloser, error) {
+	return f.Source.Open(ctx, path)
+}
+
+// Retry walks the input at path again on the next walk, even if it hasn't
+// changed. Retrying a member of an archive retries the whole archive.
+func (f *Follower) Retry(path string) {
+	if idx := strings.Index(path, ArchiveSep); idx >= 0 {
+		path = path[:idx]
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.seen, path)
+}
+
+// Walk returns when ctx is done, or when fn returns an error.
+func (f *Follower) Walk(ctx context.Context, fn func(Entry) error) error {
+	for {
+		var stop error
+		err := f.Source.Walk(ctx, func(entry Entry) error {
+			if !f.see(entry) {
+				return nil
+			}
+			if err := fn(entry); err != nil {
+				f.Retry(entry.Path)
+				stop = err
+				return err
+			}
+			return nil
+		})
+		switch {
+		case stop != nil:
+			return stop
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case err != nil && f.Log != nil:
+			f.Log.Warnw("unable to poll for inputs", "error", err, "retry", f.Interval)
+		}
+
+		timer := time.NewTimer(f.Interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// see records that the input was walked, and returns false if it already was.
+func (f *Follower) see(entry Entry) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.seen == nil {
+		f.seen = make(map[string]string)
+	}
+	if version, ok := f.seen[entry.Path]; ok && version == entry.Version {
+		return false
+	}
+	f.seen[entry.Path] = entry.Version
+	return true
+}
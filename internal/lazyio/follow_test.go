@@ -0,0 +1,121 @@
+package lazyio_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/sjansen/carpenter/internal/lazyio"
+)
+
+// listings returns the next listing each time it is walked, or an error if
+// the listing is nil.
+type listings struct {
+	walks [][]lazyio.Entry
+}
+
+func (l *listings) Open(ctx context.Context, path string) (io.ReadCloser, error) {
+	return nil, nil
+}
+
+func (l *listings) Walk(ctx context.Context, fn func(lazyio.Entry) error) error {
+	if len(l.walks) < 1 {
+		return nil
+	}
+	walk := l.walks[0]
+	l.walks = l.walks[1:]
+	if walk == nil {
+		return errors.New("throttled")
+	}
+	for _, entry := range walk {
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestFollower(t *testing.T) {
+	require := require.New(t)
+
+	a1 := lazyio.Entry{Path: "a.log", Version: "1"}
+	a2 := lazyio.Entry{Path: "a.log", Version: "2"}
+	b1 := lazyio.Entry{Path: "b.log", Version: "1"}
+	c1 := lazyio.Entry{Path: "c.log", Version: "1"}
+	f := &lazyio.Follower{
+		Source: &listings{walks: [][]lazyio.Entry{
+			{a1},
+			{a1, b1},
+			{a1, b1},
+			{a2, b1, c1},
+		}},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var actual []lazyio.Entry
+	err := f.Walk(ctx, func(entry lazyio.Entry) error {
+		actual = append(actual, entry)
+		if entry == c1 {
+			cancel()
+		}
+		return nil
+	})
+	require.ErrorIs(err, context.Canceled)
+	require.Equal([]lazyio.Entry{a1, b1, a2, c1}, actual)
+}
+
+func TestFollowerRetry(t *testing.T) {
+	require := require.New(t)
+
+	a1 := lazyio.Entry{Path: "a.tar.gz", Version: "1"}
+	b1 := lazyio.Entry{Path: "b.log", Version: "1"}
+	c1 := lazyio.Entry{Path: "c.log", Version: "1"}
+	f := &lazyio.Follower{
+		Source: &listings{walks: [][]lazyio.Entry{
+			{a1, b1},
+			nil,
+			{a1, b1},
+			{a1, b1, c1},
+		}},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var actual []lazyio.Entry
+	err := f.Walk(ctx, func(entry lazyio.Entry) error {
+		actual = append(actual, entry)
+		switch entry {
+		case a1:
+			if len(actual) < 2 {
+				f.Retry("a.tar.gz!/x.log")
+			}
+		case c1:
+			cancel()
+		}
+		return nil
+	})
+	require.ErrorIs(err, context.Canceled)
+	require.Equal([]lazyio.Entry{a1, b1, a1, c1}, actual)
+
+	f = &lazyio.Follower{
+		Source: &listings{walks: [][]lazyio.Entry{
+			{a1},
+			{a1},
+		}},
+	}
+	stop := errors.New("stop")
+	err = f.Walk(context.Background(), func(entry lazyio.Entry) error {
+		return stop
+	})
+	require.ErrorIs(err, stop)
+	err = f.Walk(context.Background(), func(entry lazyio.Entry) error {
+		return stop
+	})
+	require.ErrorIs(err, stop)
+}
@@ -1,10 +1,29 @@
 package lazyio
 
 import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
 	"compress/gzip"
+	"context"
 	"io"
+	"io/ioutil"
 	pathlib "path"
 	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// compressionExts are stripped from input paths in addition to their
+// regular extension.
+var compressionExts = []string{".bz2", ".gz", ".xz", ".zst"}
+
+var (
+	bzip2Magic = []byte("BZh")
+	gzipMagic  = []byte{0x1f, 0x8b}
+	xzMagic    = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+	zstdMagic  = []byte{0x28, 0xb5, 0x2f, 0xfd}
 )
 
 type Input struct {
@@ -29,31 +48,66 @@ func (i *Input) Close() error {
 	return err
 }
 
-func (i *Input) Open() (io.Reader, error) {
+// Open returns the contents of the input, decompressed when they start with
+// bzip2, gzip, xz, or zstd magic bytes.
+func (i *Input) Open(ctx context.Context) (io.Reader, error) {
 	if i.reader == nil {
-		r, err := i.Opener.Open(i.Path)
+		r, err := i.Opener.Open(ctx, i.Path)
 		if err != nil {
 			return nil, err
 		}
-		if strings.HasSuffix(i.Path, ".gz") {
-			i.compressed = r
-			r, err = gzip.NewReader(r)
-			if err != nil {
-				return nil, err
+		i.compressed = r
+
+		buf := bufio.NewReader(r)
+		magic, _ := buf.Peek(len(xzMagic))
+		switch {
+		case bytes.HasPrefix(magic, gzipMagic):
+			i.reader, err = gzip.NewReader(buf)
+		case bytes.HasPrefix(magic, zstdMagic):
+			var d *zstd.Decoder
+			d, err = zstd.NewReader(buf)
+			if err == nil {
+				i.reader = d.IOReadCloser()
+			}
+		case bytes.HasPrefix(magic, bzip2Magic):
+			i.reader = ioutil.NopCloser(bzip2.NewReader(buf))
+		case bytes.HasPrefix(magic, xzMagic):
+			var x *xz.Reader
+			x, err = xz.NewReader(buf)
+			if err == nil {
+				i.reader = ioutil.NopCloser(x)
 			}
+		default:
+			i.reader = ioutil.NopCloser(buf)
+		}
+		if err != nil {
+			return nil, err
 		}
-		i.reader = r
 	}
 	return i.reader, nil
 }
 
+// StripExt returns the path without its compression extensions, if any, and
+// without the extension that remains.
 func (i *Input) StripExt() string {
-	s := i.Path
-	if strings.HasSuffix(s, ".gz") {
-		s = s[:len(s)-3]
-	}
+	s := StripCompressionExt(i.Path)
 	if n := len(pathlib.Ext(s)); n > 0 {
 		s = s[:len(s)-n]
 	}
 	return s
 }
+
+// StripCompressionExt returns the path without its compression extensions.
+func StripCompressionExt(path string) string {
+	s := path
+	for stripped := true; stripped; {
+		stripped = false
+		for _, ext := range compressionExts {
+			if strings.HasSuffix(s, ext) {
+				s = s[:len(s)-len(ext)]
+				stripped = true
+			}
+		}
+	}
+	return s
+}
@@ -0,0 +1,56 @@
+package lazyio_test
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/sjansen/carpenter/internal/lazyio"
+)
+
+func TestInput(t *testing.T) {
+	require := require.New(t)
+
+	opener := &lazyio.FileReader{Dir: "testdata"}
+	for path, base := range map[string]string{
+		"bzip2":        "bzip2",
+		"gzip.log.gz":  "gzip",
+		"plain.log":    "plain",
+		"xz.log.xz":    "xz",
+		"zstd.log.zst": "zstd",
+	} {
+		input := &lazyio.Input{
+			Path:   path,
+			Opener: opener,
+		}
+		require.Equal(base, input.StripExt(), path)
+
+		r, err := input.Open(context.Background())
+		require.NoError(err, path)
+		actual, err := ioutil.ReadAll(r)
+		require.NoError(err, path)
+		require.Equal("Spoon!\n", string(actual), path)
+		require.NoError(input.Close(), path)
+	}
+}
+
+func TestInputStripExt(t *testing.T) {
+	require := require.New(t)
+
+	for path, expected := range map[string]string{
+		"2021/01/01/example.log":     "2021/01/01/example",
+		"example":                    "example",
+		"example.csv.bz2":            "example",
+		"example.log.gz":             "example",
+		"example.log.xz":             "example",
+		"example.log.zst":            "example",
+		"example.gz":                 "example",
+		"example.tar.gz.zst":         "example",
+		"example.2021-01-01.log.zst": "example.2021-01-01",
+	} {
+		input := &lazyio.Input{Path: path}
+		require.Equal(expected, input.StripExt(), path)
+	}
+}
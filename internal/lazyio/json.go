@@ -0,0 +1,132 @@
+package lazyio
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+var _ RowWriter = &JSON{}
+var _ Table = &JSON{}
+
+// JSON writes newline-delimited JSON objects.
+type JSON struct {
+	Path   string
+	Opener OutputOpener
+	// Context is used to open the output, if set.
+	Context context.Context
+	// Keys names the values passed to Write.
+	Keys []string
+
+	w    io.WriteCloser
+	buf  *bufio.Writer
+	enc  *json.Encoder
+	cols []string
+	err  error
+}
+
+func (f *JSON) Abort() error {
+	if f == nil || f.w == nil {
+		return nil
+	}
+	return Abort(f.w)
+}
+
+// Close commits the output, or returns the error that stopped it from being
+// opened or written.
+func (f *JSON) Close() error {
+	switch {
+	case f == nil:
+		return nil
+	case f.err != nil:
+		f.Abort()
+		return f.err
+	case f.w == nil:
+		return nil
+	}
+	if err := f.buf.Flush(); err != nil {
+		Abort(f.w)
+		return err
+	}
+	return f.w.Close()
+}
+
+func (f *JSON) Error() error {
+	if f == nil {
+		return nil
+	}
+	return f.err
+}
+
+func (f *JSON) Flush() {
+	if f != nil && f.buf != nil && f.err == nil {
+		f.err = f.buf.Flush()
+	}
+}
+
+// Write writes an object using Keys as the names of the values in row.
+func (f *JSON) Write(row ...string) error {
+	if f == nil || f.Opener == nil {
+		return nil
+	}
+	obj := make(map[string]string, len(row))
+	for i, v := range row {
+		if i < len(f.Keys) {
+			obj[f.Keys[i]] = v
+		}
+	}
+	return f.encode(obj)
+}
+
+// WriteHeader sets the keys written by WriteRecord. Nothing is written until
+// the first record.
+func (f *JSON) WriteHeader(cols ...string) error {
+	if f == nil {
+		return nil
+	}
+	f.cols = cols
+	return nil
+}
+
+// WriteRecord writes the header's columns present in record. Unlike CSV,
+// missing values are omitted instead of written as empty strings.
+func (f *JSON) WriteRecord(record map[string]string) error {
+	switch {
+	case f == nil || f.Opener == nil:
+		return nil
+	case f.cols == nil:
+		return fmt.Errorf("header not written: %q", f.Path)
+	}
+	obj := make(map[string]string, len(f.cols))
+	for _, k := range f.cols {
+		if v, ok := record[k]; ok {
+			obj[k] = v
+		}
+	}
+	return f.encode(obj)
+}
+
+func (f *JSON) encode(obj map[string]string) error {
+	switch {
+	case f.err != nil:
+		return f.err
+	case f.w == nil:
+		w, err := f.Opener.Open(background(f.Context), f.Path)
+		if err != nil {
+			f.err = err
+			return err
+		}
+		f.w = w
+		f.buf = bufio.NewWriter(w)
+		f.enc = json.NewEncoder(f.buf)
+		f.enc.SetEscapeHTML(false)
+	}
+
+	if err := f.enc.Encode(obj); err != nil {
+		f.err = err
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package lazyio_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/sjansen/carpenter/internal/lazyio"
+)
+
+func TestJSON(t *testing.T) {
+	require := require.New(t)
+
+	var json *lazyio.JSON
+	err := json.Write("a", "b")
+	require.NoError(err)
+	err = json.Close()
+	require.NoError(err)
+
+	o := &lazyio.BufferWriter{}
+	expectedPath := "space/magic.json"
+	json = &lazyio.JSON{
+		Path:   expectedPath,
+		Opener: o,
+		Keys:   []string{"path", "url"},
+	}
+
+	err = json.Write("/foo", "/foo?a=<b>&c")
+	require.NoError(err)
+	require.Equal([]string{expectedPath}, o.Buffers())
+
+	err = json.Close()
+	require.NoError(err)
+	buf := o.Buffer(expectedPath)
+	require.Equal(`{"path":"/foo","url":"/foo?a=<b>&c"}`+"\n", buf.String())
+}
+
+func TestJSONRecords(t *testing.T) {
+	require := require.New(t)
+
+	o := &lazyio.BufferWriter{}
+	expectedPath := "space/magic.json"
+	json := &lazyio.JSON{
+		Path:   expectedPath,
+		Opener: o,
+	}
+
+	err := json.WriteRecord(map[string]string{"a": "1"})
+	require.Error(err)
+
+	err = json.WriteHeader("a", "b", "c")
+	require.NoError(err)
+	err = json.WriteRecord(map[string]string{"a": "1", "b": "", "d": "4"})
+	require.NoError(err)
+	err = json.WriteRecord(map[string]string{"c": "3"})
+	require.NoError(err)
+	json.Flush()
+	require.NoError(json.Error())
+
+	err = json.Close()
+	require.NoError(err)
+	buf := o.Buffer(expectedPath)
+	require.Equal(`{"a":"1","b":""}`+"\n"+`{"c":"3"}`+"\n", buf.String())
+}
@@ -1,15 +1,75 @@
 package lazyio
 
-import "io"
+import (
+	"context"
+	"io"
+	"time"
+)
 
 type InputOpener interface {
-	Open(path string) (io.ReadCloser, error)
+	Open(ctx context.Context, path string) (io.ReadCloser, error)
 }
 
 type InputWalker interface {
-	Walk(fn func(path string) error) error
+	Walk(ctx context.Context, fn func(entry Entry) error) error
+}
+
+// An Entry is an input found by an InputWalker.
+type Entry struct {
+	Path string
+	// Version changes when the input is modified. It is an ETag or a
+	// modification time, or empty when unknown.
+	Version string
+	// Modified is when the input was last modified, if known.
+	Modified time.Time
+	// Err, if set, is why the input can't be read.
+	Err error
+}
+
+// A Releaser frees the resources of an input once it is no longer needed,
+// whether or not it was opened.
+type Releaser interface {
+	Release(path string) error
 }
 
 type OutputOpener interface {
-	Open(path string) (io.WriteCloser, error)
+	Open(ctx context.Context, path string) (io.WriteCloser, error)
+}
+
+// An Aborter is an output that can be discarded instead of committed by
+// Close. Outputs that aren't Aborters are closed instead.
+type Aborter interface {
+	Abort() error
+}
+
+// Abort discards w if possible, or else closes it.
+func Abort(w io.WriteCloser) error {
+	if a, ok := w.(Aborter); ok {
+		return a.Abort()
+	}
+	return w.Close()
+}
+
+// A Table writes records that share the columns named by its header.
+type Table interface {
+	Abort() error
+	Close() error
+	Error() error
+	Flush()
+	WriteHeader(cols ...string) error
+	WriteRecord(record map[string]string) error
+}
+
+// A RowWriter writes rows of values.
+type RowWriter interface {
+	Abort() error
+	Close() error
+	Write(row ...string) error
+}
+
+func background(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+	return ctx
 }
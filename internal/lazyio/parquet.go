@@ -0,0 +1,182 @@
+package lazyio
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+var _ Table = &Parquet{}
+
+// A ColumnType determines how a column is stored by typed formats.
+type ColumnType int
+
+const (
+	StringColumn ColumnType = iota
+	IntColumn
+	FloatColumn
+)
+
+type Parquet struct {
+	Path   string
+	Opener OutputOpener
+	// Context is used to open the output, if set.
+	Context context.Context
+	// Types returns the type of each column, or StringColumn when nil.
+	Types func(col string) ColumnType
+
+	w     io.WriteCloser
+	pq    *writer.CSVWriter
+	cols  []string
+	types []ColumnType
+	row   []*string
+	err   error
+}
+
+func (f *Parquet) Abort() error {
+	if f == nil || f.w == nil {
+		return nil
+	}
+	return Abort(f.w)
+}
+
+func (f *Parquet) Close() error {
+	switch {
+	case f == nil:
+		return nil
+	case f.err != nil:
+		f.Abort()
+		return f.err
+	case f.w == nil:
+		return nil
+	}
+	if f.pq != nil {
+		if err := f.pq.WriteStop(); err != nil {
+			Abort(f.w)
+			return err
+		}
+	}
+	return f.w.Close()
+}
+
+func (f *Parquet) Error() error {
+	if f == nil {
+		return nil
+	}
+	return f.err
+}
+
+// Flush is a no-op, because rows are buffered until a row group is full.
+func (f *Parquet) Flush() {}
+
+func (f *Parquet) WriteHeader(cols ...string) error {
+	switch {
+	case f == nil || f.Opener == nil:
+		return nil
+	case f.err != nil:
+		return f.err
+	case f.w != nil:
+		return fmt.Errorf("header already written: %q", f.Path)
+	}
+
+	f.cols = cols
+	f.types = make([]ColumnType, len(cols))
+	f.row = make([]*string, len(cols))
+	schema := make([]string, len(cols))
+	names := make(map[string]string, len(cols))
+	for i, col := range cols {
+		if f.Types != nil {
+			f.types[i] = f.Types(col)
+		}
+		name := parquetName(col)
+		// The Parquet library also capitalizes the first letter of each name.
+		key := name
+		if name != "" {
+			key = strings.ToUpper(name[:1]) + name[1:]
+		}
+		if other, ok := names[key]; ok {
+			f.err = fmt.Errorf("columns %q and %q have the same Parquet name: %q", other, col, name)
+			return f.err
+		}
+		names[key] = col
+		switch f.types[i] {
+		case IntColumn:
+			schema[i] = "name=" + name + ", type=INT64, repetitiontype=OPTIONAL"
+		case FloatColumn:
+			schema[i] = "name=" + name + ", type=DOUBLE, repetitiontype=OPTIONAL"
+		default:
+			schema[i] = "name=" + name + ", type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"
+		}
+	}
+
+	w, err := f.Opener.Open(background(f.Context), f.Path)
+	if err != nil {
+		f.err = err
+		return err
+	}
+	f.w = w
+
+	pq, err := writer.NewCSVWriterFromWriter(schema, w, 1)
+	if err != nil {
+		f.err = err
+		return err
+	}
+	f.pq = pq
+	return nil
+}
+
+// WriteRecord writes the values of the header's columns. Missing values, and
+// values of numeric columns that are empty, "-", or invalid, are null.
+func (f *Parquet) WriteRecord(record map[string]string) error {
+	switch {
+	case f == nil || f.Opener == nil:
+		return nil
+	case f.err != nil:
+		return f.err
+	case f.pq == nil:
+		return fmt.Errorf("header not written: %q", f.Path)
+	}
+
+	for i, k := range f.cols {
+		f.row[i] = nil
+		v, ok := record[k]
+		if !ok {
+			continue
+		}
+		switch f.types[i] {
+		case IntColumn:
+			if _, err := strconv.ParseInt(v, 10, 64); err != nil {
+				continue
+			}
+		case FloatColumn:
+			if _, err := strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		f.row[i] = &v
+	}
+
+	if err := f.pq.WriteString(f.row); err != nil {
+		f.err = err
+		return err
+	}
+	return nil
+}
+
+// parquetName replaces characters that can't be used in column names.
+func parquetName(col string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '_',
+			'0' <= r && r <= '9',
+			'a' <= r && r <= 'z',
+			'A' <= r && r <= 'Z':
+			return r
+		}
+		return '_'
+	}, col)
+}
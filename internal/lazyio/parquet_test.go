@@ -0,0 +1,90 @@
+package lazyio_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/xitongsys/parquet-go-source/buffer"
+	"github.com/xitongsys/parquet-go/reader"
+
+	"github.com/sjansen/carpenter/internal/lazyio"
+)
+
+func TestParquet(t *testing.T) {
+	require := require.New(t)
+
+	var pq *lazyio.Parquet
+	err := pq.WriteHeader("a", "b", "c")
+	require.NoError(err)
+	err = pq.WriteRecord(map[string]string{"a": "1"})
+	require.NoError(err)
+	err = pq.Close()
+	require.NoError(err)
+
+	o := &lazyio.BufferWriter{}
+	expectedPath := "space/magic.parquet"
+	pq = &lazyio.Parquet{
+		Path:   expectedPath,
+		Opener: o,
+		Types: func(col string) lazyio.ColumnType {
+			switch col {
+			case "bytes":
+				return lazyio.IntColumn
+			case "seconds":
+				return lazyio.FloatColumn
+			}
+			return lazyio.StringColumn
+		},
+	}
+
+	err = pq.WriteHeader("bytes", "seconds", "http.url")
+	require.NoError(err)
+	require.Equal([]string{expectedPath}, o.Buffers())
+
+	for _, record := range []map[string]string{
+		{"bytes": "42", "seconds": "0.5", "http.url": "/foo"},
+		{"bytes": "-", "seconds": "", "http.url": ""},
+		{"bytes": "Spoon!"},
+	} {
+		err = pq.WriteRecord(record)
+		require.NoError(err)
+	}
+	require.NoError(pq.Error())
+
+	err = pq.Close()
+	require.NoError(err)
+
+	buf := o.Buffer(expectedPath)
+	f, err := buffer.NewBufferFile(buf.Bytes())
+	require.NoError(err)
+	r, err := reader.NewParquetColumnReader(f, 1)
+	require.NoError(err)
+	defer r.ReadStop()
+	require.Equal(int64(3), r.GetNumRows())
+
+	for i, expected := range [][]interface{}{
+		{int64(42), nil, nil},
+		{0.5, nil, nil},
+		{"/foo", "", nil},
+	} {
+		actual, _, _, err := r.ReadColumnByIndex(int64(i), 3)
+		require.NoError(err)
+		require.Equal(expected, actual)
+	}
+}
+
+func TestParquetDuplicateNames(t *testing.T) {
+	require := require.New(t)
+
+	o := &lazyio.BufferWriter{}
+	pq := &lazyio.Parquet{
+		Path:   "space/magic.parquet",
+		Opener: o,
+	}
+
+	err := pq.WriteHeader("http.url", "http_url")
+	require.Error(err)
+	require.Empty(o.Buffers())
+	require.Error(pq.WriteRecord(map[string]string{"http_url": "/foo"}))
+	require.Error(pq.Error())
+}
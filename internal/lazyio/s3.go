@@ -1,6 +1,8 @@
 package lazyio
 
 import (
+	"context"
+	"errors"
 	"io"
 	pathlib "path"
 	"strings"
@@ -41,9 +43,9 @@ func NewS3Reader(io *sys.IO, uri string) (*S3Reader, error) {
 	return reader, nil
 }
 
-func (r *S3Reader) Open(path string) (io.ReadCloser, error) {
+func (r *S3Reader) Open(ctx context.Context, path string) (io.ReadCloser, error) {
 	key := pathlib.Join(r.Prefix, path)
-	result, err := r.Downloader.GetObject(&s3.GetObjectInput{
+	result, err := r.Downloader.GetObjectWithContext(ctx, &s3.GetObjectInput{
 		Bucket: aws.String(r.Bucket),
 		Key:    aws.String(key),
 	})
@@ -53,25 +55,35 @@ func (r *S3Reader) Open(path string) (io.ReadCloser, error) {
 	return result.Body, nil
 }
 
-func (r *S3Reader) Walk(fn func(string) error) error {
+func (r *S3Reader) Walk(ctx context.Context, fn func(Entry) error) error {
 	prefix := r.Prefix
 	if !strings.HasSuffix(prefix, "/") {
 		prefix += "/"
 	}
-	err := r.Downloader.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+	var fnErr error
+	err := r.Downloader.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
 		Bucket: aws.String(r.Bucket),
 		Prefix: aws.String(prefix),
 	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
 		for _, obj := range page.Contents {
 			if aws.Int64Value(obj.Size) > 0 {
 				key := aws.StringValue(obj.Key)
-				suffix := r.stripPrefix(key)
-				fn(suffix)
+				fnErr = fn(Entry{
+					Path:     r.stripPrefix(key),
+					Version:  aws.StringValue(obj.ETag),
+					Modified: aws.TimeValue(obj.LastModified),
+				})
+				if fnErr != nil {
+					return false
+				}
 			}
 		}
 		return !lastPage
 	})
-	return err
+	if err != nil {
+		return err
+	}
+	return fnErr
 }
 
 func (r *S3Reader) stripPrefix(key string) string {
@@ -108,7 +120,9 @@ func NewS3Writer(io *sys.IO, uri string) (*S3Writer, error) {
 	return writer, nil
 }
 
-func (o *S3Writer) Open(path string) (io.WriteCloser, error) {
+// Open starts uploading an object. If ctx is canceled before the object is
+// closed, the upload is aborted instead of completed.
+func (o *S3Writer) Open(ctx context.Context, path string) (io.WriteCloser, error) {
 	ch := make(chan error)
 	r, w := io.Pipe()
 	obj := &s3object{
@@ -116,26 +130,39 @@ func (o *S3Writer) Open(path string) (io.WriteCloser, error) {
 		w:  w,
 	}
 
-	go o.upload(path, r, ch)
+	go o.upload(ctx, path, r, ch)
 	return obj, nil
 }
 
-func (o *S3Writer) upload(suffix string, r io.Reader, ch chan<- error) {
+func (o *S3Writer) upload(ctx context.Context, suffix string, r *io.PipeReader, ch chan<- error) {
 	key := pathlib.Join(o.Prefix, suffix)
-	_, err := o.Uploader.Upload(&s3manager.UploadInput{
+	_, err := o.Uploader.UploadWithContext(ctx, &s3manager.UploadInput{
 		Bucket: aws.String(o.Bucket),
 		Key:    aws.String(key),
 		Body:   r,
 	})
+	// unblock writes when the upload fails before reading everything
+	r.CloseWithError(err)
 	ch <- err
 }
 
 /*
 * s3object
  */
+var _ Aborter = &s3object{}
+
+var errAborted = errors.New("upload aborted")
+
 type s3object struct {
 	ch <-chan error
-	w  io.WriteCloser
+	w  *io.PipeWriter
+}
+
+// Abort causes the upload to fail, so a partial object isn't committed.
+func (o *s3object) Abort() error {
+	o.w.CloseWithError(errAborted)
+	<-o.ch
+	return nil
 }
 
 func (o *s3object) Close() error {
@@ -1,6 +1,7 @@
 package lazyio_test
 
 import (
+	"context"
 	"io/ioutil"
 	"strings"
 	"testing"
@@ -38,7 +39,7 @@ func TestS3Opener(t *testing.T) {
 		Uploader: uploader,
 	}
 
-	w, err := o.Open("battlecry")
+	w, err := o.Open(context.Background(), "battlecry")
 	require.NoError(err)
 
 	_, err = w.Write([]byte(battlecry))
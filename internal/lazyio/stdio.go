@@ -0,0 +1,52 @@
+package lazyio
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+var _ InputOpener = &StreamReader{}
+var _ InputWalker = &StreamReader{}
+var _ OutputOpener = &StreamWriter{}
+
+// StreamPath is the path of the only input walked by a StreamReader.
+const StreamPath = "stdin"
+
+// A StreamReader walks a single input, such as stdin, named StreamPath.
+type StreamReader struct {
+	R io.Reader
+}
+
+func (sr *StreamReader) Open(ctx context.Context, path string) (io.ReadCloser, error) {
+	if path != StreamPath {
+		return nil, fmt.Errorf("no such input: %q", path)
+	}
+	return ioutil.NopCloser(sr.R), nil
+}
+
+func (sr *StreamReader) Walk(ctx context.Context, fn func(Entry) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return fn(Entry{Path: StreamPath})
+}
+
+// A StreamWriter writes every output to W, such as stdout, without closing
+// it. Outputs can't be aborted once written.
+type StreamWriter struct {
+	W io.Writer
+}
+
+func (sw *StreamWriter) Open(ctx context.Context, path string) (io.WriteCloser, error) {
+	return nopWriteCloser{sw.W}, nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
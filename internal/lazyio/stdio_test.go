@@ -0,0 +1,56 @@
+package lazyio_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/sjansen/carpenter/internal/lazyio"
+)
+
+func TestStreamReader(t *testing.T) {
+	require := require.New(t)
+
+	ctx := context.Background()
+	sr := &lazyio.StreamReader{R: strings.NewReader("Spoon!\n")}
+
+	var paths []string
+	err := sr.Walk(ctx, func(entry lazyio.Entry) error {
+		paths = append(paths, entry.Path)
+		return nil
+	})
+	require.NoError(err)
+	require.Equal([]string{lazyio.StreamPath}, paths)
+
+	r, err := sr.Open(ctx, lazyio.StreamPath)
+	require.NoError(err)
+	actual, err := ioutil.ReadAll(r)
+	require.NoError(err)
+	require.Equal("Spoon!\n", string(actual))
+	require.NoError(r.Close())
+
+	_, err = sr.Open(ctx, "magic.txt")
+	require.Error(err)
+}
+
+func TestStreamWriter(t *testing.T) {
+	require := require.New(t)
+
+	var buf bytes.Buffer
+	sw := &lazyio.StreamWriter{W: &buf}
+	csv := &lazyio.CSV{
+		Path:   "ignored.csv",
+		Opener: sw,
+	}
+	require.NoError(csv.WriteHeader("hero", "catchphrase"))
+	require.NoError(csv.WriteRecord(map[string]string{
+		"hero":        "The Tick",
+		"catchphrase": "Spoon!",
+	}))
+	require.NoError(csv.Close())
+	require.Equal("hero,catchphrase\nThe Tick,Spoon!\n", buf.String())
+}
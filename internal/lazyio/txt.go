@@ -1,21 +1,39 @@
 package lazyio
 
 import (
+	"context"
 	"io"
 )
 
 type TXT struct {
 	Path   string
 	Opener OutputOpener
+	// Context is used to open the output, if set.
+	Context context.Context
 
 	w   io.WriteCloser
 	err error
 }
 
-func (f *TXT) Close() error {
+func (f *TXT) Abort() error {
 	if f == nil || f.w == nil {
 		return nil
 	}
+	return Abort(f.w)
+}
+
+// Close commits the output, or returns the error that stopped it from being
+// opened or written.
+func (f *TXT) Close() error {
+	switch {
+	case f == nil:
+		return nil
+	case f.err != nil:
+		f.Abort()
+		return f.err
+	case f.w == nil:
+		return nil
+	}
 	return f.w.Close()
 }
 
@@ -26,7 +44,7 @@ func (f *TXT) Write(line string) error {
 	case f.err != nil:
 		return f.err
 	case f.w == nil:
-		w, err := f.Opener.Open(f.Path)
+		w, err := f.Opener.Open(background(f.Context), f.Path)
 		if err != nil {
 			f.err = err
 			return err
@@ -35,10 +53,11 @@ func (f *TXT) Write(line string) error {
 	}
 
 	_, err := f.w.Write([]byte(line))
+	if err == nil {
+		_, err = f.w.Write([]byte{'\n'})
+	}
 	if err != nil {
-		return err
+		f.err = err
 	}
-
-	_, err = f.w.Write([]byte{'\n'})
 	return err
 }
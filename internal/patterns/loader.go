@@ -7,6 +7,8 @@ import (
 
 	"go.starlark.net/resolve"
 	"go.starlark.net/starlark"
+
+	"github.com/sjansen/carpenter/internal/tokenizer"
 )
 
 func init() {
@@ -16,9 +18,11 @@ func init() {
 type patternLoader struct {
 	*starlark.Builtin // enable l.Name()
 
-	globals  starlark.StringDict
-	patterns []*pattern
-	rename   *starlark.Function
+	columns   []string
+	globals   starlark.StringDict
+	patterns  []*pattern
+	rename    *starlark.Function
+	tokenizer tokenizer.Tokenizer
 }
 
 func Load(filename string, src io.Reader) (*Patterns, error) {
@@ -28,11 +32,18 @@ func Load(filename string, src io.Reader) (*Patterns, error) {
 	}
 
 	patterns := &Patterns{
-		rename: loader.rename,
-		tests:  map[string]result{},
+		columns:   loader.columns,
+		rename:    loader.rename,
+		tests:     map[string]result{},
+		tokenizer: loader.tokenizer,
 	}
+	seen := make(map[string]bool, len(loader.patterns))
 	for _, p := range loader.patterns {
 		patterns.tree.addPattern(p, 0)
+		if !seen[p.id] {
+			seen[p.id] = true
+			patterns.ids = append(patterns.ids, p.id)
+		}
 		for raw, expected := range p.tests {
 			other, ok := patterns.tests[raw]
 			switch {
@@ -76,9 +87,15 @@ func newLoader() *patternLoader {
 	loader.Builtin = starlark.NewBuiltin("url", loader.addURL)
 
 	loader.globals = starlark.StringDict{
+		"set_columns": starlark.NewBuiltin(
+			"set_columns", loader.setColumns,
+		),
 		"set_rename_filter": starlark.NewBuiltin(
 			"set_rename_filter", loader.setRenameFilter,
 		),
+		"set_tokenizer": starlark.NewBuiltin(
+			"set_tokenizer", loader.setTokenizer,
+		),
 		"url": loader,
 	}
 
@@ -131,6 +148,49 @@ func (l *patternLoader) getIterableFromDict(parent, key string, pattern *starlar
 	return iter, nil
 }
 
+func (l *patternLoader) setColumns(
+	_ *starlark.Thread,
+	fn *starlark.Builtin,
+	args starlark.Tuple,
+	kwargs []starlark.Tuple,
+) (starlark.Value, error) {
+	var columns starlark.Iterable
+	if err := starlark.UnpackArgs(
+		fn.Name(), args, kwargs, "columns", &columns,
+	); err != nil {
+		return nil, err
+	}
+
+	result := make([]string, 0)
+	seen := make(map[string]bool)
+
+	iter := columns.Iterate()
+	defer iter.Done()
+
+	var value starlark.Value
+	for iter.Next(&value) {
+		v, ok := value.(starlark.String)
+		if !ok {
+			return nil, fmt.Errorf("%s: expected String, got %s", fn.Name(), value.Type())
+		}
+		column := v.GoString()
+		switch {
+		case column == "":
+			return nil, fmt.Errorf(`%s: invalid column: ""`, fn.Name())
+		case seen[column]:
+			return nil, fmt.Errorf("%s: column repeated: %q", fn.Name(), column)
+		}
+		seen[column] = true
+		result = append(result, column)
+	}
+	if len(result) < 1 {
+		return nil, fmt.Errorf("%s: expected at least one column", fn.Name())
+	}
+
+	l.columns = result
+	return starlark.None, nil
+}
+
 func (l *patternLoader) setRenameFilter(
 	_ *starlark.Thread,
 	fn *starlark.Builtin,
@@ -147,6 +207,63 @@ func (l *patternLoader) setRenameFilter(
 	return starlark.None, nil
 }
 
+func (l *patternLoader) setTokenizer(
+	_ *starlark.Thread,
+	fn *starlark.Builtin,
+	args starlark.Tuple,
+	kwargs []starlark.Tuple,
+) (starlark.Value, error) {
+	var callable starlark.Callable
+	var regex string
+	var fields *starlark.Dict
+	if err := starlark.UnpackArgs(
+		fn.Name(), args, kwargs,
+		"fn?", &callable, "regex?", &regex, "fields?", &fields,
+	); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case callable != nil && (regex != "" || fields != nil):
+		return nil, fmt.Errorf(`%s: "fn" can't be combined with "regex" or "fields"`, fn.Name())
+	case callable != nil:
+		l.tokenizer = &callableTokenizer{
+			Callable: callable,
+		}
+		return starlark.None, nil
+	case regex == "":
+		return nil, fmt.Errorf(`%s: expected "fn" or "regex"`, fn.Name())
+	}
+
+	renames := make(map[string]string)
+	if fields != nil {
+		for _, item := range fields.Items() {
+			key, ok := item.Index(0).(starlark.String)
+			if !ok {
+				return nil, fmt.Errorf(
+					`%s: "fields" expected String key, got %s`,
+					fn.Name(), item.Index(0).Type(),
+				)
+			}
+			value, ok := item.Index(1).(starlark.String)
+			if !ok {
+				return nil, fmt.Errorf(
+					`%s: "fields" expected String value, got %s`,
+					fn.Name(), item.Index(1).Type(),
+				)
+			}
+			renames[key.GoString()] = value.GoString()
+		}
+	}
+
+	tokenizer, err := tokenizer.NewRegex(regex, renames)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", fn.Name(), err)
+	}
+	l.tokenizer = tokenizer
+	return starlark.None, nil
+}
+
 func (l *patternLoader) transformPart(parent, child string, value starlark.Value) (part, error) {
 	switch v := value.(type) {
 	case starlark.String:
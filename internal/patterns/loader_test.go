@@ -221,7 +221,10 @@ var basicPatterns = []*pattern{{
 	},
 }}
 
-var basicTree = &Patterns{tree: tree{
+var basicTree = &Patterns{ids: []string{
+	"root", "slash-required", "no-final-slash", "optional-slash",
+	"regex", "goldilocks", "query",
+}, tree: tree{
 	id:    "root",
 	slash: mustSlash,
 	query: query{
@@ -473,7 +476,9 @@ func regexTreeFixer(t *testing.T, expected, actual *tree) {
 	}
 }
 
-var regexTree = &Patterns{tree: tree{
+var regexTree = &Patterns{ids: []string{
+	"prefix-regex", "suffix-regex", "reject-regex", "any-suffix",
+}, tree: tree{
 	children: []*child{{
 		part: &regexPart{
 			regex:    regexp.MustCompile("foo|bar"),
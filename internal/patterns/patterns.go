@@ -2,19 +2,22 @@ package patterns
 
 import (
 	"fmt"
+	"io"
 	"net/url"
-	"sort"
 	"strings"
 
 	"go.starlark.net/starlark"
 
-	"github.com/sjansen/carpenter/internal/sys"
+	"github.com/sjansen/carpenter/internal/tokenizer"
 )
 
 type Patterns struct {
-	rename *starlark.Function
-	tests  map[string]result
-	tree   tree
+	columns   []string
+	ids       []string
+	rename    *starlark.Function
+	tests     map[string]result
+	tokenizer tokenizer.Tokenizer
+	tree      tree
 }
 
 type pattern struct {
@@ -40,7 +43,7 @@ const (
 )
 
 func (p *Patterns) Match(url *url.URL) (id, normalized string, err error) {
-	results, err := p.match(url, false)
+	results, err := p.match(url, false, nil)
 	if err != nil {
 		return "", "", err
 	} else if results == nil {
@@ -50,7 +53,7 @@ func (p *Patterns) Match(url *url.URL) (id, normalized string, err error) {
 }
 
 func (p *Patterns) MatchAll(url *url.URL) (map[string]string, error) {
-	results, err := p.match(url, true)
+	results, err := p.match(url, true, nil)
 	if err != nil {
 		return nil, err
 	} else if results == nil {
@@ -89,61 +92,19 @@ func (p *Patterns) Rename(path string) (string, error) {
 	}
 }
 
-func (p *Patterns) Test(sys *sys.IO) (map[string]string, error) {
-	rawurls := make([]string, 0, len(p.tests))
-	for rawurl := range p.tests {
-		rawurls = append(rawurls, rawurl)
-	}
-	sort.Strings(rawurls)
-
-	passed := make(map[string]string, len(p.tests))
-	for _, rawurl := range rawurls {
-		sys.Log.Debugf("testing url=%q", rawurl)
-		url, err := url.Parse(rawurl)
-		if err != nil {
-			return nil, err
-		}
-
-		actual, err := p.match(url, true)
-		if err != nil {
-			return nil, err
-		}
+// Columns returns the output columns declared by set_columns(), or nil.
+func (p *Patterns) Columns() []string {
+	return p.columns
+}
 
-		expected := p.tests[rawurl]
-		switch {
-		default:
-			passed[rawurl] = expected.id
-		case len(actual) < 1 && expected.url == "":
-			passed[rawurl] = ""
-		case len(actual) < 1:
-			return nil, fmt.Errorf(
-				"url didn't match expected pattern: url=%q pattern=%q",
-				rawurl, expected.id,
-			)
-		case len(actual) > 1:
-			matches := make([]string, 0, len(actual))
-			for _, result := range actual {
-				matches = append(matches, result.id)
-			}
-			sort.Strings(matches)
-			return nil, fmt.Errorf(
-				"url matched by multiple patterns: url=%q patterns=%q",
-				rawurl, matches,
-			)
-		case actual[0].id != expected.id:
-			return nil, fmt.Errorf(
-				"unexpected pattern matched: expected=%q actual=%q (url=%q)",
-				expected.id, actual[0].id, rawurl,
-			)
-		case actual[0].url != expected.url:
-			return nil, fmt.Errorf(
-				"unexpected result: expected=%q actual=%q (pattern=%q url=%q)",
-				expected.url, actual[0].url, expected.id, rawurl,
-			)
-		}
-	}
+// IDs returns the id of each pattern, in the order they were declared.
+func (p *Patterns) IDs() []string {
+	return p.ids
+}
 
-	return passed, nil
+// Tokenizer returns the tokenizer declared by set_tokenizer(), or nil.
+func (p *Patterns) Tokenizer() tokenizer.Tokenizer {
+	return p.tokenizer
 }
 
 func (p *Patterns) TestCases() map[string]string {
@@ -158,7 +119,19 @@ func (p *Patterns) TestCases() map[string]string {
 	return testcases
 }
 
-func (p *Patterns) match(url *url.URL, matchAll bool) ([]*result, error) {
+// Explain writes a trace of the steps taken to match url, and returns the
+// same result as Match.
+func (p *Patterns) Explain(w io.Writer, url *url.URL) (id, normalized string, err error) {
+	results, err := p.match(url, false, &trace{w: w})
+	if err != nil {
+		return "", "", err
+	} else if results == nil {
+		return "", "", nil
+	}
+	return results[0].id, results[0].url, nil
+}
+
+func (p *Patterns) match(url *url.URL, matchAll bool, tr *trace) ([]*result, error) {
 	if len(url.Path) < 1 || url.Path[0] != '/' {
 		err := fmt.Errorf(`URLs must start with "/": %q`, url.Path)
 		return nil, err
@@ -166,7 +139,8 @@ func (p *Patterns) match(url *url.URL, matchAll bool) ([]*result, error) {
 
 	var results []*result
 	if url.Path == "/" && p.tree.id != "" {
-		matches, err := p.tree.recordMatch(1, url.Query())
+		tr.printf(0, "root: matched %q", p.tree.id)
+		matches, err := p.tree.recordMatch(1, url.Query(), tr)
 		if err != nil {
 			return nil, err
 		}
@@ -176,7 +150,7 @@ func (p *Patterns) match(url *url.URL, matchAll bool) ([]*result, error) {
 		}
 	}
 
-	matches, err := p.tree.match(url.Path[1:], url.Query(), 0, matchAll)
+	matches, err := p.tree.match(url.Path[1:], url.Query(), 0, matchAll, tr)
 	if err != nil {
 		return nil, err
 	}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/sjansen/carpenter/internal/logger"
 	"github.com/sjansen/carpenter/internal/sys"
+	"github.com/sjansen/carpenter/internal/tokenizer"
 )
 
 func TestMatch(t *testing.T) {
@@ -70,6 +71,58 @@ url("second", path={"prefix": [(".+", "ANY")], "suffix": "/"}, query={}, tests={
 	require.Equal(expected, actual)
 }
 
+func TestExplain(t *testing.T) {
+	require := require.New(t)
+
+	r := bytes.NewBufferString(`
+url("slash", path={"prefix": ["foo"], "suffix": "/"}, query={}, tests={})
+url("any", path={"prefix": [], "suffix": (".+", lambda x: "ANY", "^foo/bar$")}, query={}, tests={})
+url("bar", path={"prefix": ["foo", "bar"], "suffix": "/?"}, query={
+    "match": {"q": "X", "token": None},
+}, tests={})
+	`)
+
+	patterns, err := Load("<buffer>", r)
+	require.NoError(err)
+
+	url, err := url.Parse("/foo/bar?token=secret&q=apples&page=2")
+	require.NoError(err)
+
+	var trace bytes.Buffer
+	id, normalized, err := patterns.Explain(&trace, url)
+	require.NoError(err)
+	require.Equal("bar", id)
+	require.Equal("/foo/bar?page=2&q=X", normalized)
+
+	expected := `try plain "foo" against "foo": matched
+  no patterns continue with "bar"
+try suffix regex ".+" against "foo/bar": rejected by "^foo/bar$"
+try plain "foo" against "foo": matched
+  try plain "bar" against "bar": matched
+    end of path: matched "bar"
+      unmatched param "page": kept ["2"]
+      param "q": rewrote ["apples"] to ["X"]
+      param "token": removed ["secret"]
+`
+	require.Equal(expected, trace.String())
+
+	url, err = url.Parse("/baz/qux?token=secret")
+	require.NoError(err)
+
+	trace.Reset()
+	id, normalized, err = patterns.Explain(&trace, url)
+	require.NoError(err)
+	require.Equal("any", id)
+	require.Equal("/ANY?token=secret", normalized)
+
+	expected = `try plain "foo" against "baz": no match
+try suffix regex ".+" against "baz/qux": matched, pattern "any"
+  unmatched param "token": kept ["secret"]
+normalized "baz/qux" to "ANY"
+`
+	require.Equal(expected, trace.String())
+}
+
 func TestMatchErrors(t *testing.T) {
 	files, _ := filepath.Glob("testdata/match-errors/*.star")
 	for _, tc := range files {
@@ -162,6 +215,109 @@ func TestTest(t *testing.T) {
 	require.Equal(expected, actual)
 }
 
+func TestColumns(t *testing.T) {
+	require := require.New(t)
+
+	r, err := os.Open("testdata/columns.star")
+	require.NoError(err)
+
+	patterns, err := Load("columns.star", r)
+	require.NoError(err)
+	require.Equal(
+		[]string{"request_url", "normalized_url", "url_pattern", "elb_status_code"},
+		patterns.Columns(),
+	)
+
+	r, err = os.Open("testdata/basic.star")
+	require.NoError(err)
+
+	patterns, err = Load("basic.star", r)
+	require.NoError(err)
+	require.Nil(patterns.Columns())
+}
+
+func TestTokenizer(t *testing.T) {
+	require := require.New(t)
+
+	for filename, tc := range map[string]struct {
+		line     string
+		expected map[string]string
+	}{
+		"testdata/tokenizer-fn.star": {
+			line: "GET|/foo|200",
+			expected: map[string]string{
+				"request_verb": "GET",
+				"request_url":  "/foo",
+				"status":       "200",
+			},
+		},
+		"testdata/tokenizer-regex.star": {
+			line: `GET /foo 200 "curl/7.46.0"`,
+			expected: map[string]string{
+				"request_verb": "GET",
+				"request_url":  "/foo",
+				"status":       "200",
+				"user_agent":   "curl/7.46.0",
+			},
+		},
+	} {
+		r, err := os.Open(filename)
+		require.NoError(err)
+
+		patterns, err := Load(filename, r)
+		require.NoError(err)
+
+		tokenizer := patterns.Tokenizer()
+		require.NotNil(tokenizer, filename)
+		require.Equal(tc.expected, tokenizer.Tokenize(tc.line), filename)
+		require.Nil(tokenizer.Tokenize("Spoon!"), filename)
+	}
+}
+
+func TestTokenizerError(t *testing.T) {
+	require := require.New(t)
+
+	r, err := os.Open("testdata/tokenizer-fn.star")
+	require.NoError(err)
+
+	patterns, err := Load("tokenizer-fn.star", r)
+	require.NoError(err)
+
+	fallible, ok := patterns.Tokenizer().(tokenizer.Fallible)
+	require.True(ok)
+
+	tokens, err := fallible.TryTokenize("Spoon!")
+	require.NoError(err)
+	require.Nil(tokens)
+
+	tokens, err = fallible.TryTokenize("GET|/foo|Spoon!")
+	require.Error(err)
+	require.Nil(tokens)
+}
+
+func TestTokenizerTypeErrors(t *testing.T) {
+	require := require.New(t)
+
+	r, err := os.Open("testdata/tokenizer-types.star")
+	require.NoError(err)
+
+	patterns, err := Load("tokenizer-types.star", r)
+	require.NoError(err)
+
+	fallible, ok := patterns.Tokenizer().(tokenizer.Fallible)
+	require.True(ok)
+
+	tokens, err := fallible.TryTokenize("Spoon!")
+	require.NoError(err)
+	require.Nil(tokens)
+
+	for _, line := range []string{"list", "key"} {
+		tokens, err = fallible.TryTokenize(line)
+		require.Error(err, line)
+		require.Nil(tokens, line)
+	}
+}
+
 func TestTestErrors(t *testing.T) {
 	files, _ := filepath.Glob("testdata/test-errors/*.star")
 	for _, tc := range files {
@@ -0,0 +1,158 @@
+package patterns
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+
+	"github.com/sjansen/carpenter/internal/sys"
+)
+
+// Kinds of test failures.
+const (
+	FailedError              = "error"
+	FailedNoMatch            = "no match"
+	FailedMultipleMatches    = "multiple matches"
+	FailedWrongPattern       = "wrong pattern"
+	FailedWrongNormalization = "wrong normalization"
+)
+
+// FailureKinds lists the kinds of test failures in the order they're reported.
+var FailureKinds = []string{
+	FailedError,
+	FailedNoMatch,
+	FailedMultipleMatches,
+	FailedWrongPattern,
+	FailedWrongNormalization,
+}
+
+// A TestCase is the result of a test declared by a pattern.
+type TestCase struct {
+	URL string
+	// Pattern is the id of the pattern that declared the test.
+	Pattern string
+	// Unmatched is true if the URL is expected not to match Pattern.
+	Unmatched bool
+	// Failure is the kind of failure, or "" if the test passed.
+	Failure string
+	Err     error
+}
+
+// TestResults are the results of every test declared by a pattern file.
+type TestResults struct {
+	// Cases are sorted by URL.
+	Cases []*TestCase
+}
+
+// Err returns nil if every test passed, the failure of the only test that
+// failed, or an error counting the failures.
+func (r *TestResults) Err() error {
+	failures := r.Failures()
+	switch len(failures) {
+	case 0:
+		return nil
+	case 1:
+		return failures[0].Err
+	}
+	return fmt.Errorf("%d of %d tests failed", len(failures), len(r.Cases))
+}
+
+// Failures returns the tests that failed.
+func (r *TestResults) Failures() []*TestCase {
+	var failures []*TestCase
+	for _, c := range r.Cases {
+		if c.Failure != "" {
+			failures = append(failures, c)
+		}
+	}
+	return failures
+}
+
+// Test runs every test declared by the patterns, returning the expected
+// pattern of each URL or "" for URLs that shouldn't match.
+func (p *Patterns) Test(sys *sys.IO) (map[string]string, error) {
+	results := p.RunTests(sys)
+	if err := results.Err(); err != nil {
+		return nil, err
+	}
+
+	passed := make(map[string]string, len(results.Cases))
+	for _, c := range results.Cases {
+		if c.Unmatched {
+			passed[c.URL] = ""
+		} else {
+			passed[c.URL] = c.Pattern
+		}
+	}
+	return passed, nil
+}
+
+// RunTests runs every test declared by the patterns, continuing after
+// failures.
+func (p *Patterns) RunTests(sys *sys.IO) *TestResults {
+	rawurls := make([]string, 0, len(p.tests))
+	for rawurl := range p.tests {
+		rawurls = append(rawurls, rawurl)
+	}
+	sort.Strings(rawurls)
+
+	results := &TestResults{
+		Cases: make([]*TestCase, 0, len(rawurls)),
+	}
+	for _, rawurl := range rawurls {
+		sys.Log.Debugf("testing url=%q", rawurl)
+		expected := p.tests[rawurl]
+		c := &TestCase{
+			URL:       rawurl,
+			Pattern:   expected.id,
+			Unmatched: expected.url == "",
+		}
+		c.Failure, c.Err = p.test(rawurl, expected)
+		results.Cases = append(results.Cases, c)
+	}
+	return results
+}
+
+func (p *Patterns) test(rawurl string, expected result) (string, error) {
+	url, err := url.Parse(rawurl)
+	if err != nil {
+		return FailedError, err
+	}
+
+	actual, err := p.match(url, true, nil)
+	if err != nil {
+		return FailedError, err
+	}
+
+	switch {
+	default:
+		return "", nil
+	case len(actual) < 1 && expected.url == "":
+		return "", nil
+	case len(actual) < 1:
+		return FailedNoMatch, fmt.Errorf(
+			"url didn't match expected pattern: url=%q pattern=%q",
+			rawurl, expected.id,
+		)
+	case len(actual) > 1:
+		matches := make([]string, 0, len(actual))
+		for _, result := range actual {
+			matches = append(matches, result.id)
+		}
+		sort.Strings(matches)
+		return FailedMultipleMatches, fmt.Errorf(
+			"url matched by multiple patterns: url=%q patterns=%q",
+			rawurl, matches,
+		)
+	case actual[0].id != expected.id:
+		return FailedWrongPattern, fmt.Errorf(
+			"unexpected pattern matched: expected=%q actual=%q (url=%q)",
+			expected.id, actual[0].id, rawurl,
+		)
+	case actual[0].url != expected.url:
+		return FailedWrongNormalization, fmt.Errorf(
+			"unexpected result: expected=%q actual=%q (pattern=%q url=%q)",
+			expected.url, actual[0].url, expected.id, rawurl,
+		)
+	}
+}
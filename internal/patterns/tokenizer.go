@@ -0,0 +1,58 @@
+package patterns
+
+import (
+	"fmt"
+
+	"go.starlark.net/starlark"
+
+	"github.com/sjansen/carpenter/internal/tokenizer"
+)
+
+var _ tokenizer.Fallible = &callableTokenizer{}
+
+// A callableTokenizer converts lines to fields using a Starlark function
+// that returns a dict, or None when a line can't be parsed.
+type callableTokenizer struct {
+	starlark.Callable
+}
+
+func (t *callableTokenizer) Tokenize(line string) map[string]string {
+	result, _ := t.TryTokenize(line)
+	return result
+}
+
+func (t *callableTokenizer) TryTokenize(line string) (map[string]string, error) {
+	thread := &starlark.Thread{}
+	args := starlark.Tuple{starlark.String(line)}
+	value, err := starlark.Call(thread, t.Callable, args, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var dict *starlark.Dict
+	switch v := value.(type) {
+	case starlark.NoneType:
+		return nil, nil
+	case *starlark.Dict:
+		dict = v
+	default:
+		return nil, fmt.Errorf("%s: expected Dict or None, got %s", t.Name(), value.Type())
+	}
+
+	result := make(map[string]string, dict.Len())
+	for _, item := range dict.Items() {
+		key, ok := item.Index(0).(starlark.String)
+		if !ok {
+			return nil, fmt.Errorf("%s: expected String keys, got %s", t.Name(), item.Index(0).Type())
+		}
+		switch v := item.Index(1).(type) {
+		case starlark.NoneType:
+			continue
+		case starlark.String:
+			result[key.GoString()] = v.GoString()
+		default:
+			result[key.GoString()] = v.String()
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,122 @@
+package patterns
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// A trace writes the steps taken to match a URL. Its methods do nothing when
+// the trace is nil, so matching without a trace costs little.
+type trace struct {
+	w     io.Writer
+	depth int
+}
+
+// printf writes a step indented by depth.
+func (tr *trace) printf(depth int, format string, args ...interface{}) {
+	if tr == nil {
+		return
+	}
+	tr.depth = depth
+	tr.write(depth, format, args...)
+}
+
+func (tr *trace) write(depth int, format string, args ...interface{}) {
+	fmt.Fprintf(tr.w, "%s%s\n", strings.Repeat("  ", depth), fmt.Sprintf(format, args...))
+}
+
+// part traces an attempt to match part against a segment of the path.
+func (tr *trace) part(depth int, p part, path string, ok bool) {
+	if tr == nil {
+		return
+	}
+	var result string
+	switch p := p.(type) {
+	case *plainPart:
+		result = "no match"
+		if ok {
+			result = "matched"
+		}
+	case *regexPart:
+		switch {
+		case ok:
+			result = "matched"
+		case p.reject != nil && p.regex.MatchString(path):
+			result = fmt.Sprintf("rejected by %q", p.reject)
+		default:
+			result = "no match"
+		}
+	}
+	tr.try(depth, p, path, result)
+}
+
+// try traces the result of an attempt to match part against path.
+func (tr *trace) try(depth int, p part, path, result string) {
+	if tr == nil {
+		return
+	}
+	switch p := p.(type) {
+	case *plainPart:
+		tr.printf(depth, "try plain %q against %q: %s", p.value, path, result)
+	case *regexPart:
+		kind := "regex"
+		if p.suffix {
+			kind = "suffix regex"
+		}
+		tr.printf(depth, "try %s %q against %q: %s", kind, p.regex, path, result)
+	}
+}
+
+// consumed traces a suffix regex that matched the rest of the path, and so
+// matched the pattern id unless it was rejected for reason.
+func (tr *trace) consumed(depth int, p part, path, id, reason string) {
+	if tr == nil {
+		return
+	}
+	switch {
+	case id == "":
+		tr.try(depth, p, path, "matched, but no pattern ends here")
+	case reason != "":
+		tr.try(depth, p, path, fmt.Sprintf("matched, but pattern %q %s", id, reason))
+	default:
+		tr.try(depth, p, path, fmt.Sprintf("matched, pattern %q", id))
+	}
+}
+
+func (tr *trace) normalized(depth int, path, normalized string) {
+	if path != normalized {
+		tr.printf(depth, "normalized %q to %q", path, normalized)
+	}
+}
+
+// param traces how a query param was rewritten, beneath the previous step.
+func (tr *trace) param(kind, key string, before, after []string) {
+	if tr == nil {
+		return
+	}
+	depth := tr.depth + 1
+	switch {
+	case after == nil:
+		tr.write(depth, "%s %q: removed %q", kind, key, before)
+	case strings.Join(before, "&") == strings.Join(after, "&"):
+		tr.write(depth, "%s %q: kept %q", kind, key, before)
+	default:
+		tr.write(depth, "%s %q: rewrote %q to %q", kind, key, before, after)
+	}
+}
+
+// keys returns the keys of query, sorted when tracing so traces are
+// repeatable.
+func (tr *trace) keys(query url.Values) []string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	if tr != nil {
+		sort.Strings(keys)
+	}
+	return keys
+}
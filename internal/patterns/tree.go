@@ -54,34 +54,42 @@ func (t *tree) addPattern(p *pattern, depth int) {
 	t.query = p.query
 }
 
-func (t *tree) match(path string, query url.Values, depth int, matchAll bool) ([]*match, error) {
+func (t *tree) match(path string, query url.Values, depth int, matchAll bool, tr *trace) ([]*match, error) {
 	switch path {
 	case "":
 		switch {
 		case t.id == "":
+			tr.printf(depth, "end of path: no pattern ends here")
 			return nil, nil
 		case t.slash == maySlash:
-			return t.recordMatch(depth, query)
+			tr.printf(depth, "end of path: matched %q", t.id)
+			return t.recordMatch(depth, query, tr)
 		case t.slash == neverSlash:
-			return t.recordMatch(depth, query)
+			tr.printf(depth, "end of path: matched %q", t.id)
+			return t.recordMatch(depth, query, tr)
 		}
+		tr.printf(depth, "end of path: rejected %q, which requires a trailing slash", t.id)
 		return nil, nil
 	case "/":
 		switch {
 		case t.id == "":
-			return t.matchSuffix("", query, depth, matchAll)
+			tr.printf(depth, "trailing slash: trying suffixes")
+			return t.matchSuffix("", query, depth, matchAll, tr)
 		case t.slash == maySlash:
-			return t.recordMatch(depth, query)
+			tr.printf(depth, "trailing slash: matched %q", t.id)
+			return t.recordMatch(depth, query, tr)
 		case t.slash == mustSlash:
-			return t.recordMatch(depth, query)
+			tr.printf(depth, "trailing slash: matched %q", t.id)
+			return t.recordMatch(depth, query, tr)
 		}
+		tr.printf(depth, "trailing slash: rejected %q, which forbids a trailing slash", t.id)
 		return nil, nil
 	}
 
-	return t.matchChildren(path, query, depth+1, matchAll)
+	return t.matchChildren(path, query, depth+1, matchAll, tr)
 }
 
-func (t *tree) matchChildren(path string, query url.Values, depth int, matchAll bool) ([]*match, error) {
+func (t *tree) matchChildren(path string, query url.Values, depth int, matchAll bool, tr *trace) ([]*match, error) {
 	var prefix, suffix string
 	idx := strings.Index(path, "/")
 	switch idx {
@@ -95,6 +103,10 @@ func (t *tree) matchChildren(path string, query url.Values, depth int, matchAll
 		suffix = path[idx+1:]
 	}
 
+	if len(t.children) < 1 {
+		tr.printf(depth-1, "no patterns continue with %q", path)
+	}
+
 	var matches []*match
 	for _, child := range t.children {
 		part := child.part
@@ -106,8 +118,16 @@ func (t *tree) matchChildren(path string, query url.Values, depth int, matchAll
 			suffix = ""
 		}
 
-		if part.match(prefix) {
-			tmp, err := child.tree.match(suffix, query, depth, matchAll)
+		ok := part.match(prefix)
+		if ok {
+			var tmp []*match
+			var err error
+			if part.greedy() {
+				tmp, err = child.tree.matchConsumed(part, prefix, query, depth-1, tr)
+			} else {
+				tr.part(depth-1, part, prefix, ok)
+				tmp, err = child.tree.match(suffix, query, depth, matchAll, tr)
+			}
 			if err != nil {
 				return nil, err
 			}
@@ -118,6 +138,7 @@ func (t *tree) matchChildren(path string, query url.Values, depth int, matchAll
 				if err != nil {
 					return nil, err
 				}
+				tr.normalized(depth-1, prefix, normalized)
 
 				for _, m := range tmp {
 					m.parts = append(m.parts, normalized)
@@ -128,13 +149,29 @@ func (t *tree) matchChildren(path string, query url.Values, depth int, matchAll
 				}
 				matches = append(matches, tmp...)
 			}
+		} else {
+			tr.part(depth-1, part, prefix, ok)
 		}
 	}
 
 	return matches, nil
 }
 
-func (t *tree) matchSuffix(path string, query url.Values, depth int, matchAll bool) ([]*match, error) {
+// matchConsumed matches t after a suffix regex consumed the rest of the path.
+func (t *tree) matchConsumed(p part, path string, query url.Values, depth int, tr *trace) ([]*match, error) {
+	switch {
+	case t.id == "":
+		tr.consumed(depth, p, path, t.id, "")
+		return nil, nil
+	case t.slash == mustSlash:
+		tr.consumed(depth, p, path, t.id, "requires a trailing slash")
+		return nil, nil
+	}
+	tr.consumed(depth, p, path, t.id, "")
+	return t.recordMatch(depth, query, tr)
+}
+
+func (t *tree) matchSuffix(path string, query url.Values, depth int, matchAll bool, tr *trace) ([]*match, error) {
 	var matches []*match
 	for _, child := range t.children {
 		part := child.part
@@ -142,8 +179,12 @@ func (t *tree) matchSuffix(path string, query url.Values, depth int, matchAll bo
 			continue
 		}
 
-		if part.match(path) {
-			tmp, err := t.recordMatch(depth, query)
+		ok := part.match(path)
+		if !ok {
+			tr.part(depth+1, part, path, ok)
+		} else {
+			tr.consumed(depth+1, part, path, child.tree.id, "")
+			tmp, err := t.recordMatch(depth, query, tr)
 			if err != nil {
 				return nil, err
 			}
@@ -154,6 +195,7 @@ func (t *tree) matchSuffix(path string, query url.Values, depth int, matchAll bo
 				if err != nil {
 					return nil, err
 				}
+				tr.normalized(depth+1, path, normalized)
 
 				for _, m := range tmp {
 					m.id = child.tree.id
@@ -171,8 +213,8 @@ func (t *tree) matchSuffix(path string, query url.Values, depth int, matchAll bo
 	return matches, nil
 }
 
-func (t *tree) recordMatch(depth int, query url.Values) ([]*match, error) {
-	q, err := t.rewriteQuery(query)
+func (t *tree) recordMatch(depth int, query url.Values, tr *trace) ([]*match, error) {
+	q, err := t.rewriteQuery(query, tr)
 	if err != nil {
 		return nil, err
 	}
@@ -188,11 +230,12 @@ func (t *tree) recordMatch(depth int, query url.Values) ([]*match, error) {
 	return []*match{&m}, nil
 }
 
-func (t *tree) rewriteQuery(query url.Values) (string, error) {
+func (t *tree) rewriteQuery(query url.Values, tr *trace) (string, error) {
 	result := url.Values{}
 
 	thread := &starlark.Thread{}
-	for key, values := range query {
+	for _, key := range tr.keys(query) {
+		values := query[key]
 		param, ok := t.query.match[key]
 		switch {
 		case ok:
@@ -200,14 +243,17 @@ func (t *tree) rewriteQuery(query url.Values) (string, error) {
 			if err != nil {
 				return "", err
 			}
+			tr.param("param", key, query[key], values)
 			result[key] = values
 		case t.query.other != nil:
 			values, err := t.query.other.normalize(thread, t.query.dedup, key, values)
 			if err != nil {
 				return "", err
 			}
+			tr.param("other param", key, query[key], values)
 			result[key] = values
 		default:
+			tr.param("unmatched param", key, values, values)
 			result[key] = values
 		}
 	}
@@ -0,0 +1,146 @@
+package pipeline
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/sjansen/carpenter/internal/shapes"
+)
+
+// Coverage counts the records matched by each pattern, and the shapes of
+// the paths of unrecognized records, across completed tasks.
+type Coverage struct {
+	mu           sync.Mutex
+	hits         map[string]int
+	unrecognized map[string]int
+	skipped      int
+}
+
+// counts are the hits and misses of a single task, added to the Coverage
+// when the task completes.
+type counts struct {
+	hits         map[string]int
+	unrecognized map[string]int
+}
+
+func newCounts() *counts {
+	return &counts{
+		hits:         make(map[string]int),
+		unrecognized: make(map[string]int),
+	}
+}
+
+func (c *counts) hit(id string) {
+	if c != nil {
+		c.hits[id]++
+	}
+}
+
+func (c *counts) miss(path string) {
+	if c != nil {
+		c.unrecognized[shapes.Path(path)]++
+	}
+}
+
+func (c *Coverage) add(counts *counts) {
+	if c == nil || counts == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.hits == nil {
+		c.hits = make(map[string]int)
+		c.unrecognized = make(map[string]int)
+	}
+	for k, v := range counts.hits {
+		c.hits[k] += v
+	}
+	for k, v := range counts.unrecognized {
+		c.unrecognized[k] += v
+	}
+}
+
+// skip counts an input skipped as complete, whose records aren't counted.
+func (c *Coverage) skip() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.skipped++
+}
+
+// Hits returns the number of records matched by each pattern.
+func (c *Coverage) Hits() map[string]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	hits := make(map[string]int, len(c.hits))
+	for k, v := range c.hits {
+		hits[k] = v
+	}
+	return hits
+}
+
+// WriteReport writes the hits of each pattern in ids, most frequent first,
+// followed by the patterns that never matched and the top most frequent
+// shapes of unrecognized paths. The report starts with a note when inputs
+// were skipped, since patterns might only match their records.
+func (c *Coverage) WriteReport(w io.Writer, ids []string, top int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var hit, dead []string
+	for _, id := range ids {
+		if c.hits[id] > 0 {
+			hit = append(hit, id)
+		} else {
+			dead = append(dead, id)
+		}
+	}
+	sortByCount(hit, c.hits)
+
+	unrecognized := make([]string, 0, len(c.unrecognized))
+	for shape := range c.unrecognized {
+		unrecognized = append(unrecognized, shape)
+	}
+	sortByCount(unrecognized, c.unrecognized)
+	if len(unrecognized) > top {
+		unrecognized = unrecognized[:top]
+	}
+
+	var err error
+	printf := func(format string, args ...interface{}) {
+		if err == nil {
+			_, err = fmt.Fprintf(w, format, args...)
+		}
+	}
+	if c.skipped > 0 {
+		printf("note: inputs skipped as complete, and not counted: %d\n\n", c.skipped)
+	}
+	printf("hits (%d of %d patterns):\n", len(hit), len(ids))
+	for _, id := range hit {
+		printf("  %d\t%s\n", c.hits[id], id)
+	}
+	printf("\ndead patterns (%d):\n", len(dead))
+	for _, id := range dead {
+		printf("  %s\n", id)
+	}
+	printf("\nunrecognized paths (top %d of %d shapes):\n", len(unrecognized), len(c.unrecognized))
+	for _, shape := range unrecognized {
+		printf("  %d\t%s\n", c.unrecognized[shape], shape)
+	}
+	return err
+}
+
+// sortByCount sorts keys by descending count, then alphabetically.
+func sortByCount(keys []string, counts map[string]int) {
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := counts[keys[i]], counts[keys[j]]
+		if a != b {
+			return a > b
+		}
+		return keys[i] < keys[j]
+	})
+}
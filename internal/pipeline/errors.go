@@ -0,0 +1,32 @@
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrCanceled is returned by AddTask after a failure when FailFast is set.
+var ErrCanceled = errors.New("canceled after failure")
+
+// A TaskError records why the task for an input failed.
+type TaskError struct {
+	Path string
+	Err  error
+}
+
+func (e *TaskError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Err)
+}
+
+func (e *TaskError) Unwrap() error {
+	return e.Err
+}
+
+// A Summary counts the tasks added to a pipeline.
+type Summary struct {
+	Tasks    int
+	Canceled int
+	Skipped  int
+	// Failures are the inputs whose last task failed, sorted by path.
+	Failures []*TaskError
+}
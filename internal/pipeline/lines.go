@@ -0,0 +1,62 @@
+package pipeline
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// A lineReader returns the non-empty lines of an input, and can buffer the
+// first few so they can be examined before being processed.
+type lineReader struct {
+	buf     *bufio.Reader
+	pending []string
+}
+
+func newLineReader(r io.Reader) *lineReader {
+	return &lineReader{buf: bufio.NewReader(r)}
+}
+
+// Next returns the next non-empty line, trimmed of surrounding whitespace.
+func (r *lineReader) Next() (string, error) {
+	if len(r.pending) > 0 {
+		line := r.pending[0]
+		r.pending = r.pending[1:]
+		return line, nil
+	}
+	return r.read()
+}
+
+// Peek returns up to n lines without consuming them.
+func (r *lineReader) Peek(n int) ([]string, error) {
+	for len(r.pending) < n {
+		line, err := r.read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		r.pending = append(r.pending, line)
+	}
+	if len(r.pending) < n {
+		n = len(r.pending)
+	}
+	return r.pending[:n], nil
+}
+
+func (r *lineReader) read() (string, error) {
+	for {
+		line, err := r.buf.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return "", err
+		}
+
+		// the last line might not end with a newline
+		line = strings.TrimSpace(line)
+		if line != "" {
+			return line, nil
+		} else if err != nil {
+			return "", err
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package pipeline
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLineReader(t *testing.T) {
+	for _, input := range []string{
+		"a\n\n  b \nc\n",
+		"a\n\n  b \nc",
+		"a\n\n  b \nc\n \n",
+	} {
+		require := require.New(t)
+
+		r := newLineReader(strings.NewReader(input))
+		peeked, err := r.Peek(2)
+		require.NoError(err)
+		require.Equal([]string{"a", "b"}, peeked)
+
+		var lines []string
+		for {
+			line, err := r.Next()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(err)
+			lines = append(lines, line)
+		}
+		require.Equal([]string{"a", "b", "c"}, lines, input)
+	}
+}
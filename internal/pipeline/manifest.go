@@ -0,0 +1,159 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/sjansen/carpenter/internal/lazyio"
+)
+
+// ManifestName is the name of the manifest written next to results.
+const ManifestName = ".carpenter-manifest.json"
+
+// A Manifest records the inputs that have been transformed, so unchanged
+// inputs can be skipped when a transform is repeated or resumed.
+type Manifest struct {
+	Path   string
+	Opener lazyio.OutputOpener
+	// Interval is the minimum time between saves by Flush.
+	Interval time.Duration
+
+	mu     sync.Mutex
+	inputs map[string]manifestEntry
+	dirty  bool
+	saved  time.Time
+
+	saving sync.Mutex
+}
+
+type manifestEntry struct {
+	Version string   `json:"version"`
+	Output  string   `json:"output"`
+	Columns []string `json:"columns,omitempty"`
+}
+
+type manifestFile struct {
+	Inputs map[string]manifestEntry `json:"inputs"`
+}
+
+// Load replaces the recorded inputs with the contents of a saved manifest.
+func (m *Manifest) Load(r io.Reader) error {
+	var f manifestFile
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.inputs = f.Inputs
+	m.dirty = false
+	return nil
+}
+
+// Complete returns true if the input was transformed when it had version, to
+// output with the same columns. Inputs with unknown versions are never
+// complete, and inputs transformed to nothing match any output.
+func (m *Manifest) Complete(path, version, output string, columns []string) bool {
+	if m == nil || version == "" {
+		return false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, ok := m.inputs[path]
+	switch {
+	case !ok || entry.Version != version:
+		return false
+	case entry.Output != "" && entry.Output != output:
+		return false
+	case len(entry.Columns) != len(columns):
+		return false
+	}
+	for i, column := range columns {
+		if entry.Columns[i] != column {
+			return false
+		}
+	}
+	return true
+}
+
+// Record marks the input as transformed to output with columns, or to
+// nothing if output is empty.
+func (m *Manifest) Record(path, version, output string, columns []string) {
+	if m == nil || version == "" {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.inputs == nil {
+		m.inputs = make(map[string]manifestEntry)
+	}
+	m.inputs[path] = manifestEntry{
+		Version: version,
+		Output:  output,
+		Columns: columns,
+	}
+	m.dirty = true
+}
+
+// Flush saves the manifest if it has changed and wasn't saved recently.
+func (m *Manifest) Flush(ctx context.Context) error {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	stale := m.dirty && time.Since(m.saved) >= m.Interval
+	m.mu.Unlock()
+	if !stale {
+		return nil
+	}
+	return m.Save(ctx)
+}
+
+// Save writes the manifest if it has changed since it was loaded or saved.
+func (m *Manifest) Save(ctx context.Context) error {
+	if m == nil {
+		return nil
+	}
+	m.saving.Lock()
+	defer m.saving.Unlock()
+
+	m.mu.Lock()
+	if !m.dirty {
+		m.mu.Unlock()
+		return nil
+	}
+	f := manifestFile{
+		Inputs: make(map[string]manifestEntry, len(m.inputs)),
+	}
+	for k, v := range m.inputs {
+		f.Inputs[k] = v
+	}
+	m.dirty = false
+	m.saved = time.Now()
+	m.mu.Unlock()
+
+	err := m.write(ctx, &f)
+	if err != nil {
+		m.mu.Lock()
+		m.dirty = true
+		m.mu.Unlock()
+	}
+	return err
+}
+
+func (m *Manifest) write(ctx context.Context, f *manifestFile) error {
+	w, err := m.Opener.Open(ctx, m.Path)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(f); err != nil {
+		lazyio.Abort(w)
+		return err
+	}
+	return w.Close()
+}
@@ -0,0 +1,181 @@
+package pipeline_test
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/sjansen/carpenter/internal/lazyio"
+	"github.com/sjansen/carpenter/internal/patterns"
+	"github.com/sjansen/carpenter/internal/pipeline"
+	"github.com/sjansen/carpenter/internal/sys"
+	"github.com/sjansen/carpenter/internal/tokenizer"
+)
+
+func TestManifest(t *testing.T) {
+	require := require.New(t)
+
+	r, err := os.Open("testdata/alb.star")
+	require.NoError(err)
+
+	patterns, err := patterns.Load("alb.star", r)
+	require.NoError(err)
+
+	ctx := context.Background()
+	result := &lazyio.BufferWriter{}
+	manifest := &pipeline.Manifest{
+		Path:   pipeline.ManifestName,
+		Opener: result,
+	}
+	require.False(manifest.Complete("alb.log", "v1", "alb.csv", nil))
+
+	run := func(entries ...lazyio.Entry) *pipeline.Summary {
+		p := &pipeline.Pipeline{
+			Patterns:  patterns,
+			Tokenizer: tokenizer.ALB,
+			IO:        sys.Discard(),
+			Source:    &lazyio.FileReader{Dir: "testdata/src"},
+			Result:    result,
+			Manifest:  manifest,
+		}
+		p.Start(ctx)
+		for _, entry := range entries {
+			p.AddTask(ctx, entry)
+		}
+		summary := p.Wait()
+		require.NoError(manifest.Save(ctx))
+		return summary
+	}
+
+	summary := run(
+		lazyio.Entry{Path: "alb.log", Version: "v1"},
+		lazyio.Entry{Path: "missing.log", Version: "v1"},
+	)
+	require.Equal(0, summary.Skipped)
+	require.Len(summary.Failures, 1)
+	require.True(manifest.Complete("alb.log", "v1", "alb.csv", nil))
+	require.False(manifest.Complete("alb.log", "v2", "alb.csv", nil))
+	require.False(manifest.Complete("missing.log", "v1", "alb.csv", nil))
+
+	saved := result.Buffer(pipeline.ManifestName).String()
+	require.Equal(`{
+  "inputs": {
+    "alb.log": {
+      "version": "v1",
+      "output": "alb.csv"
+    }
+  }
+}
+`, saved)
+
+	summary = run(
+		lazyio.Entry{Path: "alb.log", Version: "v1"},
+		lazyio.Entry{Path: "alb.log"},
+	)
+	require.Equal(1, summary.Skipped)
+	require.Equal(1, summary.Tasks)
+
+	loaded := &pipeline.Manifest{}
+	require.NoError(loaded.Load(strings.NewReader(saved)))
+	require.True(loaded.Complete("alb.log", "v1", "alb.csv", nil))
+	require.False(loaded.Complete("alb.log", "", "alb.csv", nil))
+	require.False(loaded.Complete("alb.log", "v1", "alb.json", nil))
+	require.False(loaded.Complete("alb.log", "v1", "alb.csv", []string{"request_url"}))
+}
+
+func TestManifestOptionsChanged(t *testing.T) {
+	require := require.New(t)
+
+	r, err := os.Open("testdata/alb.star")
+	require.NoError(err)
+
+	patterns, err := patterns.Load("alb.star", r)
+	require.NoError(err)
+
+	ctx := context.Background()
+	manifest := &pipeline.Manifest{
+		Path:   pipeline.ManifestName,
+		Opener: &lazyio.BufferWriter{},
+	}
+
+	run := func(format string, columns []string) (*pipeline.Summary, []string) {
+		result := &lazyio.BufferWriter{}
+		p := &pipeline.Pipeline{
+			Patterns:     patterns,
+			Tokenizer:    tokenizer.ALB,
+			IO:           sys.Discard(),
+			Source:       &lazyio.FileReader{Dir: "testdata/src"},
+			Result:       result,
+			ResultFormat: format,
+			Columns:      columns,
+			Manifest:     manifest,
+		}
+		p.Start(ctx)
+		p.AddTask(ctx, lazyio.Entry{Path: "alb.log", Version: "v1"})
+		return p.Wait(), result.Buffers()
+	}
+
+	summary, outputs := run("csv", nil)
+	require.Empty(summary.Failures)
+	require.Equal([]string{"alb.csv"}, outputs)
+
+	summary, outputs = run("csv", nil)
+	require.Equal(1, summary.Skipped)
+	require.Empty(outputs)
+
+	summary, outputs = run("json", nil)
+	require.Equal(0, summary.Skipped)
+	require.Equal([]string{"alb.json"}, outputs)
+
+	summary, outputs = run("json", []string{"request_url", "url_pattern"})
+	require.Equal(0, summary.Skipped)
+	require.Equal([]string{"alb.json"}, outputs)
+
+	summary, outputs = run("json", []string{"request_url", "url_pattern"})
+	require.Equal(1, summary.Skipped)
+	require.Empty(outputs)
+}
+
+func TestManifestOutputErrors(t *testing.T) {
+	require := require.New(t)
+
+	r, err := os.Open("testdata/alb.star")
+	require.NoError(err)
+
+	patterns, err := patterns.Load("alb.star", r)
+	require.NoError(err)
+
+	ctx := context.Background()
+	manifest := &pipeline.Manifest{
+		Path:   pipeline.ManifestName,
+		Opener: &lazyio.BufferWriter{},
+	}
+
+	run := func(result lazyio.OutputOpener) *pipeline.Summary {
+		p := &pipeline.Pipeline{
+			Patterns:  patterns,
+			Tokenizer: tokenizer.ALB,
+			IO:        sys.Discard(),
+			Source:    &lazyio.FileReader{Dir: "testdata/src"},
+			Result:    result,
+			Manifest:  manifest,
+		}
+		p.Start(ctx)
+		p.AddTask(ctx, lazyio.Entry{Path: "alb.log", Version: "v1"})
+		return p.Wait()
+	}
+
+	summary := run(&failingOpener{})
+	require.Len(summary.Failures, 1)
+	require.False(manifest.Complete("alb.log", "v1", "alb.csv", nil))
+
+	result := &lazyio.BufferWriter{}
+	summary = run(result)
+	require.Empty(summary.Failures)
+	require.Equal(0, summary.Skipped)
+	require.True(manifest.Complete("alb.log", "v1", "alb.csv", nil))
+	require.Equal([]string{"alb.csv"}, result.Buffers())
+}
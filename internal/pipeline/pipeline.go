@@ -1,8 +1,11 @@
 package pipeline
 
 import (
+	"context"
+	"errors"
 	pathlib "path"
 	"runtime"
+	"sort"
 	"strconv"
 	"sync"
 
@@ -14,99 +17,379 @@ import (
 )
 
 type Pipeline struct {
-	Patterns  *patterns.Patterns
-	Tokenizer *tokenizer.Tokenizer
+	// Columns fixes the order and subset of result columns. When nil, the
+	// columns of each result are the fields of its first record.
+	Columns  []string
+	Patterns *patterns.Patterns
+	// Tokenizer is detected separately for each input when nil.
+	Tokenizer tokenizer.Tokenizer
 	UAParser  *uaparser.Parser
 
 	IO     *sys.IO
 	Source lazyio.InputOpener
 	Result lazyio.OutputOpener
 	Debug  lazyio.OutputOpener
+	// ResultFormat is "csv" (the default), "json", or "parquet".
+	ResultFormat string
+	// DebugFormat is "csv" (the default) or "json".
+	DebugFormat string
+	// FailFast cancels the remaining tasks after the first failure.
+	FailFast bool
+	// Workers is the number of tasks run concurrently, and so the number of
+	// open inputs. The default is the number of CPUs.
+	Workers int
+	// MaxOpenOutputs limits the number of result and debug outputs open at
+	// once. It should be at least TaskOutputs. The default is no limit.
+	MaxOpenOutputs int
+	// Manifest, if set, records completed inputs, and inputs it already
+	// records as complete are skipped.
+	Manifest *Manifest
+	// SkipRename names outputs after inputs without applying the rename
+	// filter of Patterns.
+	SkipRename bool
+	// Coverage, if set, counts the records matched by each pattern in
+	// completed tasks.
+	Coverage *Coverage
+	// Retry, if set, is called with the path of each failed input, so it can
+	// be walked again.
+	Retry func(path string)
 
-	ch chan<- *Task
-	wg sync.WaitGroup
+	ch      chan<- *Task
+	done    chan struct{}
+	once    sync.Once
+	outputs *semaphore
+	wg      sync.WaitGroup
+
+	mu      sync.Mutex
+	summary Summary
+	// failures is keyed by path, so a retried input is counted as one task,
+	// and only while it is still failing.
+	failures map[string]*TaskError
 }
 
-func (p *Pipeline) AddTask(path string) error {
+// AddTask queues the input at path, blocking until a worker is available.
+func (p *Pipeline) AddTask(ctx context.Context, entry lazyio.Entry) error {
+	path := entry.Path
+	if entry.Err != nil {
+		p.count(path)
+		p.fail(path, entry.Err)
+		return entry.Err
+	}
+
 	input := &lazyio.Input{
 		Path:   path,
 		Opener: p.Source,
 	}
 	base := input.StripExt()
-	renamed, err := p.Patterns.Rename(base)
+	renamed := base
+	var err error
+	if !p.SkipRename {
+		renamed, err = p.Patterns.Rename(base)
+	}
 	switch {
 	case err != nil:
+		p.count(path)
+		p.fail(path, err)
+		p.release(path)
 		return err
 	case renamed == "":
+		p.release(path)
 		return nil
 	case renamed != base:
 		p.IO.Log.Debugw("renaming file", "base", base, "renamed", renamed)
 	}
 
+	output := p.resultPath(renamed)
+	if p.Manifest.Complete(path, entry.Version, output, p.Columns) {
+		p.IO.Log.Debugw("skipping complete input", "path", path, "version", entry.Version)
+		p.mu.Lock()
+		p.summary.Skipped++
+		p.mu.Unlock()
+		p.Coverage.skip()
+		p.release(path)
+		return nil
+	}
+
 	task := &Task{
+		version:   entry.Version,
+		output:    output,
+		columns:   p.Columns,
 		patterns:  p.Patterns,
 		tokenizer: p.Tokenizer,
 		uaparser:  p.UAParser,
 		src:       input,
-		dst: lazyio.CSV{
-			Opener: p.Result,
-			Path:   renamed + ".csv",
-		},
+		counts:    p.newCounts(),
+		dst:       p.newResult(ctx, output),
+		debug:     p.newDebug(ctx, renamed),
+	}
+
+	p.count(path)
+
+	select {
+	case <-p.done:
+		p.cancel(task)
+		return ErrCanceled
+	default:
+	}
+	select {
+	case p.ch <- task:
+		return nil
+	case <-p.done:
+		p.cancel(task)
+		return ErrCanceled
+	case <-ctx.Done():
+		p.cancel(task)
+		return ctx.Err()
+	}
+}
+
+func (p *Pipeline) newCounts() *counts {
+	if p.Coverage == nil {
+		return nil
+	}
+	return newCounts()
+}
+
+// count counts the input at path as a task, unless it is a retry of a failed
+// task.
+func (p *Pipeline) count(path string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.failures[path]; !ok {
+		p.summary.Tasks++
+	}
+}
+
+func (p *Pipeline) cancel(t *Task) {
+	p.IO.Log.Debugw("canceled task", "path", t.src.Path)
+	p.mu.Lock()
+	p.summary.Canceled++
+	p.mu.Unlock()
+	p.release(t.src.Path)
+}
+
+// release frees the resources of the input at path, if Source holds any.
+func (p *Pipeline) release(path string) {
+	if r, ok := p.Source.(lazyio.Releaser); ok {
+		if err := r.Release(path); err != nil {
+			p.IO.Log.Warnw("unable to release input", "path", path, "error", err)
+		}
+	}
+}
+
+func (p *Pipeline) fail(path string, err error) {
+	p.mu.Lock()
+	p.failures[path] = &TaskError{
+		Path: path,
+		Err:  err,
+	}
+	p.mu.Unlock()
+	if errors.Is(err, context.Canceled) {
+		p.IO.Log.Debugw("task interrupted", "path", path)
+	} else {
+		p.IO.Log.Errorw("task failed", "path", path, "error", err)
+		// retry after recording the failure, so the retry isn't counted
+		if p.Retry != nil {
+			p.Retry(path)
+		}
+	}
+	if p.FailFast {
+		p.once.Do(func() {
+			close(p.done)
+		})
 	}
+}
+
+// succeed forgets any earlier failure of the input at path.
+func (p *Pipeline) succeed(path string) {
+	p.mu.Lock()
+	delete(p.failures, path)
+	p.mu.Unlock()
+}
+
+// resultPath returns the path of the result for an input renamed to path.
+func (p *Pipeline) resultPath(path string) string {
+	ext := lazyio.OutputExt(p.Result)
+	switch p.ResultFormat {
+	case "json":
+		return path + ".json" + ext
+	case "parquet":
+		return path + ".parquet" + ext
+	default:
+		return path + ".csv" + ext
+	}
+}
+
+func (p *Pipeline) newResult(ctx context.Context, path string) lazyio.Table {
+	switch p.ResultFormat {
+	case "json":
+		return &lazyio.JSON{
+			Opener:  p.Result,
+			Context: ctx,
+			Path:    path,
+		}
+	case "parquet":
+		return &lazyio.Parquet{
+			Opener:  p.Result,
+			Context: ctx,
+			Path:    path,
+			Types:   columnType,
+		}
+	default:
+		return &lazyio.CSV{
+			Opener:  p.Result,
+			Context: ctx,
+			Path:    path,
+		}
+	}
+}
 
-	if p.Debug != nil {
-		task.debug = debug{
-			normalize: lazyio.CSV{
-				Opener: p.Debug,
-				Path:   pathlib.Join("normalize", renamed+".csv"),
-			},
-			parse: lazyio.CSV{
-				Opener: p.Debug,
-				Path:   pathlib.Join("parse", renamed+".csv"),
-			},
-			tokenize: lazyio.TXT{
-				Opener: p.Debug,
-				Path:   pathlib.Join("tokenize", renamed+".txt"),
-			},
-			unrecognized: lazyio.CSV{
-				Opener: p.Debug,
-				Path:   pathlib.Join("unrecognized", renamed+".csv"),
-			},
+func (p *Pipeline) newDebug(ctx context.Context, path string) debug {
+	ext := lazyio.OutputExt(p.Debug)
+	d := debug{
+		tokenize: lazyio.TXT{
+			Opener:  p.Debug,
+			Context: ctx,
+			Path:    pathlib.Join("tokenize", path+".txt"+ext),
+		},
+	}
+	switch p.DebugFormat {
+	case "json":
+		d.normalize = &lazyio.JSON{
+			Opener:  p.Debug,
+			Context: ctx,
+			Path:    pathlib.Join("normalize", path+".json"+ext),
+			Keys:    []string{"url", "error"},
+		}
+		d.parse = &lazyio.JSON{
+			Opener:  p.Debug,
+			Context: ctx,
+			Path:    pathlib.Join("parse", path+".json"+ext),
+			Keys:    []string{"url", "error"},
+		}
+		d.unrecognized = &lazyio.JSON{
+			Opener:  p.Debug,
+			Context: ctx,
+			Path:    pathlib.Join("unrecognized", path+".json"+ext),
+			Keys:    []string{"path", "url"},
+		}
+	default:
+		d.normalize = &lazyio.CSV{
+			Opener:  p.Debug,
+			Context: ctx,
+			Path:    pathlib.Join("normalize", path+".csv"+ext),
+		}
+		d.parse = &lazyio.CSV{
+			Opener:  p.Debug,
+			Context: ctx,
+			Path:    pathlib.Join("parse", path+".csv"+ext),
+		}
+		d.unrecognized = &lazyio.CSV{
+			Opener:  p.Debug,
+			Context: ctx,
+			Path:    pathlib.Join("unrecognized", path+".csv"+ext),
 		}
 	}
+	return d
+}
 
-	p.ch <- task
-	return nil
+// TaskOutputs returns the number of outputs each task might open.
+func (p *Pipeline) TaskOutputs() int {
+	if p.Debug == nil {
+		return 1
+	}
+	return 5
 }
 
-func (p *Pipeline) Start() {
+// Start starts the workers, which stop running tasks when ctx is canceled.
+func (p *Pipeline) Start(ctx context.Context) {
 	ch := make(chan *Task)
-	for i := runtime.NumCPU(); i > 0; i-- {
+	p.done = make(chan struct{})
+	p.failures = make(map[string]*TaskError)
+	if p.MaxOpenOutputs > 0 {
+		p.outputs = newSemaphore(p.MaxOpenOutputs)
+	}
+	workers := p.Workers
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+	for i := workers; i > 0; i-- {
 		p.wg.Add(1)
-		go worker(p.IO, i, ch, &p.wg)
+		go p.worker(ctx, i, ch)
 	}
 	p.ch = ch
 }
 
-func (p *Pipeline) Wait() {
+// Wait blocks until every task has completed, and then summarizes them.
+func (p *Pipeline) Wait() *Summary {
 	close(p.ch)
 	p.wg.Wait()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	summary := p.summary
+	for _, failure := range p.failures {
+		summary.Failures = append(summary.Failures, failure)
+	}
+	sort.Slice(summary.Failures, func(i, j int) bool {
+		return summary.Failures[i].Path < summary.Failures[j].Path
+	})
+	return &summary
+}
+
+func (p *Pipeline) run(ctx context.Context, t *Task) error {
+	if p.outputs == nil {
+		return t.Run(ctx)
+	}
+	n := p.TaskOutputs()
+	if n > p.MaxOpenOutputs {
+		n = p.MaxOpenOutputs
+	}
+	p.outputs.acquire(n)
+	defer p.outputs.release(n)
+	return t.Run(ctx)
 }
 
-func worker(io *sys.IO, id int, ch <-chan *Task, wg *sync.WaitGroup) {
-	log := io.Log
+func (p *Pipeline) worker(ctx context.Context, id int, ch <-chan *Task) {
+	log := p.IO.Log
 	log.Debugw("starting worker", "id", id)
 	prefix := strconv.Itoa(id) + ":"
 	var i uint64
 	for t := range ch {
+		select {
+		case <-p.done:
+			p.cancel(t)
+			continue
+		case <-ctx.Done():
+			p.cancel(t)
+			continue
+		default:
+		}
 		t.id = prefix + strconv.FormatUint(i, 10)
 		log.Debugw("processing task", "worker", id, "task", t.id, "path", t.src.Path)
-		if err := t.Run(); err != nil {
+		if err := p.run(ctx, t); err != nil {
 			log.Debugw("task error returned", "task", t.id, "path", t.src.Path)
+			p.fail(t.src.Path, err)
+		} else {
+			p.succeed(t.src.Path)
+			p.Coverage.add(t.counts)
+			if p.Manifest != nil {
+				p.Manifest.Record(t.src.Path, t.version, t.committed, t.columns)
+				if err := p.Manifest.Flush(ctx); err != nil {
+					log.Warnw("unable to save manifest", "error", err)
+				}
+			}
+		}
+		p.release(t.src.Path)
+		if dropped := t.Dropped(); len(dropped) > 0 {
+			log.Warnw("dropped undeclared fields", "task", t.id, "path", t.src.Path, "fields", dropped)
+		}
+		if t.format != "" {
+			log.Debugw("detected format", "task", t.id, "format", t.format)
 		}
 		log.Debugw("completed task", "worker", id, "task", t.id, "path", t.src.Path)
 		i++
 	}
 	log.Debugw("stopping worker", "id", id)
-	wg.Done()
+	p.wg.Done()
 }
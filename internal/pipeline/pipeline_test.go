@@ -1,10 +1,16 @@
 package pipeline_test
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -43,8 +49,8 @@ func TestPipeline(t *testing.T) {
 		Debug:     debug,
 	}
 
-	pipeline.Start()
-	pipeline.AddTask("alb.log")
+	pipeline.Start(context.Background())
+	pipeline.AddTask(context.Background(), lazyio.Entry{Path: "alb.log"})
 	pipeline.Wait()
 
 	expected, err := ioutil.ReadFile("testdata/dst/alb.csv")
@@ -76,3 +82,476 @@ func TestPipeline(t *testing.T) {
 		}
 	}
 }
+
+func TestPipelineJSON(t *testing.T) {
+	require := require.New(t)
+
+	r, err := os.Open("testdata/alb.star")
+	require.NoError(err)
+
+	patterns, err := patterns.Load("alb.star", r)
+	require.NoError(err)
+
+	debug := &lazyio.BufferWriter{}
+	result := &lazyio.BufferWriter{}
+	pipeline := &pipeline.Pipeline{
+		Patterns:     patterns,
+		Tokenizer:    tokenizer.ALB,
+		IO:           sys.Discard(),
+		Source:       &lazyio.FileReader{Dir: "testdata/src"},
+		Result:       result,
+		Debug:        debug,
+		ResultFormat: "json",
+		DebugFormat:  "json",
+	}
+
+	pipeline.Start(context.Background())
+	pipeline.AddTask(context.Background(), lazyio.Entry{Path: "alb.log"})
+	pipeline.Wait()
+
+	require.Equal([]string{"alb.json"}, result.Buffers())
+	lines := strings.Split(strings.TrimSpace(result.Buffer("alb.json").String()), "\n")
+	require.NotEmpty(lines)
+	for _, line := range lines {
+		var record map[string]string
+		require.NoError(json.Unmarshal([]byte(line), &record))
+		require.Contains(record, "request_url")
+		require.NotContains(record, "client_ua_family")
+	}
+
+	buffers := debug.Buffers()
+	sort.Strings(buffers)
+	require.Equal(
+		[]string{"parse/alb.json", "tokenize/alb.txt", "unrecognized/alb.json"},
+		buffers,
+	)
+	line, err := debug.Buffer("unrecognized/alb.json").ReadString('\n')
+	require.NoError(err)
+	var record map[string]string
+	require.NoError(json.Unmarshal([]byte(line), &record))
+	require.Contains(record, "path")
+	require.Contains(record, "url")
+}
+
+func TestPipelineColumns(t *testing.T) {
+	require := require.New(t)
+
+	r, err := os.Open("testdata/alb.star")
+	require.NoError(err)
+
+	patterns, err := patterns.Load("alb.star", r)
+	require.NoError(err)
+
+	result := &lazyio.BufferWriter{}
+	pipeline := &pipeline.Pipeline{
+		Columns:   []string{"request_url", "url_pattern", "target_list"},
+		Patterns:  patterns,
+		Tokenizer: tokenizer.ALB,
+		IO:        sys.Discard(),
+		Source:    &lazyio.FileReader{Dir: "testdata/src"},
+		Result:    result,
+	}
+
+	pipeline.Start(context.Background())
+	pipeline.AddTask(context.Background(), lazyio.Entry{Path: "alb.log"})
+	pipeline.Wait()
+
+	require.Equal([]string{"alb.csv"}, result.Buffers())
+	lines := strings.Split(result.Buffer("alb.csv").String(), "\n")
+	require.Equal("request_url,url_pattern,target_list", lines[0])
+	require.Equal(
+		"http://www.example.com:80/,root,",
+		lines[1],
+	)
+}
+
+func TestPipelineFailures(t *testing.T) {
+	for _, failFast := range []bool{false, true} {
+		require := require.New(t)
+
+		patterns, err := patterns.Load("rename.star", strings.NewReader(
+			"def rename(path):\n"+
+				"    if path == 'bad':\n"+
+				"        fail('bad path')\n"+
+				"    return path\n"+
+				"set_rename_filter(rename)\n",
+		))
+		require.NoError(err)
+
+		p := &pipeline.Pipeline{
+			Patterns: patterns,
+			IO:       sys.Discard(),
+			Source:   &lazyio.FileReader{Dir: "testdata/src"},
+			Result:   &lazyio.BufferWriter{},
+			FailFast: failFast,
+		}
+
+		p.Start(context.Background())
+		require.Error(p.AddTask(context.Background(), lazyio.Entry{Path: "bad.log"}))
+		err = p.AddTask(context.Background(), lazyio.Entry{Path: "missing.log"})
+		if failFast {
+			require.ErrorIs(err, pipeline.ErrCanceled)
+		} else {
+			require.NoError(err)
+		}
+		summary := p.Wait()
+
+		require.Equal(2, summary.Tasks)
+		if failFast {
+			require.Equal(1, summary.Canceled)
+			require.Len(summary.Failures, 1)
+		} else {
+			require.Equal(0, summary.Canceled)
+			require.Len(summary.Failures, 2)
+			require.Equal("missing.log", summary.Failures[1].Path)
+		}
+		require.Equal("bad.log", summary.Failures[0].Path)
+	}
+}
+
+func TestPipelineEntryErrors(t *testing.T) {
+	require := require.New(t)
+
+	r, err := os.Open("testdata/alb.star")
+	require.NoError(err)
+
+	patterns, err := patterns.Load("alb.star", r)
+	require.NoError(err)
+
+	result := &lazyio.BufferWriter{}
+	p := &pipeline.Pipeline{
+		Patterns:  patterns,
+		Tokenizer: tokenizer.ALB,
+		IO:        sys.Discard(),
+		Source:    &lazyio.FileReader{Dir: "testdata/src"},
+		Result:    result,
+	}
+
+	p.Start(context.Background())
+	require.Error(p.AddTask(context.Background(), lazyio.Entry{
+		Path: "broken.tar.gz",
+		Err:  errors.New("unable to extract"),
+	}))
+	require.NoError(p.AddTask(context.Background(), lazyio.Entry{Path: "alb.log"}))
+	summary := p.Wait()
+
+	require.Equal(2, summary.Tasks)
+	require.Len(summary.Failures, 1)
+	require.Equal("broken.tar.gz", summary.Failures[0].Path)
+	require.Equal([]string{"alb.csv"}, result.Buffers())
+}
+
+func TestPipelineRetry(t *testing.T) {
+	require := require.New(t)
+
+	r, err := os.Open("testdata/alb.star")
+	require.NoError(err)
+
+	patterns, err := patterns.Load("alb.star", r)
+	require.NoError(err)
+
+	var retried []string
+	result := &lazyio.BufferWriter{}
+	p := &pipeline.Pipeline{
+		Patterns:  patterns,
+		Tokenizer: tokenizer.ALB,
+		IO:        sys.Discard(),
+		Source:    &lazyio.FileReader{Dir: "testdata/src"},
+		Result:    result,
+		Retry: func(path string) {
+			retried = append(retried, path)
+		},
+	}
+
+	p.Start(context.Background())
+	require.Error(p.AddTask(context.Background(), lazyio.Entry{
+		Path: "alb.log",
+		Err:  errors.New("unable to download"),
+	}))
+	require.Equal([]string{"alb.log"}, retried)
+	require.NoError(p.AddTask(context.Background(), lazyio.Entry{Path: "alb.log"}))
+	summary := p.Wait()
+
+	require.Equal(1, summary.Tasks)
+	require.Empty(summary.Failures)
+	require.Equal([]string{"alb.csv"}, result.Buffers())
+}
+
+// failingOpener fails to open every output.
+type failingOpener struct{}
+
+func (o *failingOpener) Open(ctx context.Context, path string) (io.WriteCloser, error) {
+	return nil, errors.New("permission denied")
+}
+
+func TestPipelineOutputErrors(t *testing.T) {
+	for _, format := range []string{"csv", "json", "parquet"} {
+		require := require.New(t)
+
+		r, err := os.Open("testdata/alb.star")
+		require.NoError(err)
+
+		patterns, err := patterns.Load("alb.star", r)
+		require.NoError(err)
+
+		p := &pipeline.Pipeline{
+			Patterns:     patterns,
+			Tokenizer:    tokenizer.ALB,
+			IO:           sys.Discard(),
+			Source:       &lazyio.FileReader{Dir: "testdata/src"},
+			Result:       &failingOpener{},
+			ResultFormat: format,
+		}
+
+		p.Start(context.Background())
+		require.NoError(p.AddTask(context.Background(), lazyio.Entry{Path: "alb.log"}))
+		summary := p.Wait()
+
+		require.Equal(1, summary.Tasks, format)
+		require.Len(summary.Failures, 1, format)
+		require.Equal("alb.log", summary.Failures[0].Path, format)
+	}
+}
+
+func TestPipelineDebugErrors(t *testing.T) {
+	for _, format := range []string{"csv", "json"} {
+		require := require.New(t)
+
+		r, err := os.Open("testdata/alb.star")
+		require.NoError(err)
+
+		patterns, err := patterns.Load("alb.star", r)
+		require.NoError(err)
+
+		result := &lazyio.BufferWriter{}
+		p := &pipeline.Pipeline{
+			Patterns:    patterns,
+			Tokenizer:   tokenizer.ALB,
+			IO:          sys.Discard(),
+			Source:      &lazyio.FileReader{Dir: "testdata/src"},
+			Result:      result,
+			Debug:       &failingOpener{},
+			DebugFormat: format,
+		}
+
+		p.Start(context.Background())
+		require.NoError(p.AddTask(context.Background(), lazyio.Entry{Path: "alb.log"}))
+		summary := p.Wait()
+
+		require.Len(summary.Failures, 1, format)
+		require.Equal("alb.log", summary.Failures[0].Path, format)
+	}
+}
+
+type countingOpener struct {
+	lazyio.BufferWriter
+
+	mu      sync.Mutex
+	open    int
+	maxOpen int
+}
+
+func (o *countingOpener) Open(ctx context.Context, path string) (io.WriteCloser, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.open++
+	if o.open > o.maxOpen {
+		o.maxOpen = o.open
+	}
+	w, err := o.BufferWriter.Open(ctx, path)
+	return &countingWriter{WriteCloser: w, o: o}, err
+}
+
+type countingWriter struct {
+	io.WriteCloser
+	o *countingOpener
+}
+
+func (w *countingWriter) Close() error {
+	w.o.mu.Lock()
+	w.o.open--
+	w.o.mu.Unlock()
+	return w.WriteCloser.Close()
+}
+
+func TestPipelineMaxOpenOutputs(t *testing.T) {
+	require := require.New(t)
+
+	r, err := os.Open("testdata/alb.star")
+	require.NoError(err)
+
+	patterns, err := patterns.Load("alb.star", r)
+	require.NoError(err)
+
+	result := &countingOpener{}
+	p := &pipeline.Pipeline{
+		Patterns:       patterns,
+		Tokenizer:      tokenizer.ALB,
+		IO:             sys.Discard(),
+		Source:         &lazyio.FileReader{Dir: "testdata/src"},
+		Result:         result,
+		Workers:        4,
+		MaxOpenOutputs: 2,
+	}
+	require.Equal(1, p.TaskOutputs())
+
+	p.Start(context.Background())
+	for i := 0; i < 16; i++ {
+		require.NoError(p.AddTask(context.Background(), lazyio.Entry{Path: "alb.log"}))
+	}
+	summary := p.Wait()
+
+	require.Empty(summary.Failures)
+	require.Equal(16, summary.Tasks)
+	require.LessOrEqual(result.maxOpen, 2)
+	require.Equal(0, result.open)
+}
+
+func TestPipelineCanceled(t *testing.T) {
+	require := require.New(t)
+
+	r, err := os.Open("testdata/alb.star")
+	require.NoError(err)
+
+	patterns, err := patterns.Load("alb.star", r)
+	require.NoError(err)
+
+	result := &lazyio.BufferWriter{}
+	p := &pipeline.Pipeline{
+		Patterns:  patterns,
+		Tokenizer: tokenizer.ALB,
+		IO:        sys.Discard(),
+		Source:    &lazyio.FileReader{Dir: "testdata/src"},
+		Result:    result,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	p.Start(ctx)
+	err = p.AddTask(ctx, lazyio.Entry{Path: "alb.log"})
+	if err != nil {
+		require.ErrorIs(err, context.Canceled)
+	}
+	summary := p.Wait()
+
+	require.Equal(1, summary.Tasks)
+	require.Equal(1, summary.Canceled)
+	require.Empty(summary.Failures)
+	require.Empty(result.Buffers())
+}
+
+func TestPipelineStream(t *testing.T) {
+	require := require.New(t)
+
+	src, err := ioutil.ReadFile("testdata/alb.star")
+	require.NoError(err)
+	src = append(src, "\ndef skip(path):\n    return None\n\nset_rename_filter(skip)\n"...)
+	patterns, err := patterns.Load("alb.star", strings.NewReader(string(src)))
+	require.NoError(err)
+
+	r, err := os.Open("testdata/src/alb.log")
+	require.NoError(err)
+	defer r.Close()
+
+	var stdout strings.Builder
+	p := &pipeline.Pipeline{
+		Columns:    []string{"request_url", "url_pattern"},
+		Patterns:   patterns,
+		Tokenizer:  tokenizer.ALB,
+		IO:         sys.Discard(),
+		Source:     &lazyio.StreamReader{R: r},
+		Result:     &lazyio.StreamWriter{W: &stdout},
+		SkipRename: true,
+	}
+
+	p.Start(context.Background())
+	require.NoError(p.AddTask(context.Background(), lazyio.Entry{Path: lazyio.StreamPath}))
+	summary := p.Wait()
+	require.Equal(1, summary.Tasks)
+	require.Empty(summary.Failures)
+
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	require.Equal("request_url,url_pattern", lines[0])
+	require.Equal("http://www.example.com:80/,root", lines[1])
+}
+
+func TestPipelineCoverage(t *testing.T) {
+	require := require.New(t)
+
+	r, err := os.Open("testdata/alb.star")
+	require.NoError(err)
+
+	patterns, err := patterns.Load("alb.star", r)
+	require.NoError(err)
+
+	coverage := &pipeline.Coverage{}
+	p := &pipeline.Pipeline{
+		Patterns:  patterns,
+		Tokenizer: tokenizer.ALB,
+		IO:        sys.Discard(),
+		Source:    &lazyio.FileReader{Dir: "testdata/src"},
+		Result:    &lazyio.BufferWriter{},
+		Coverage:  coverage,
+	}
+
+	p.Start(context.Background())
+	p.AddTask(context.Background(), lazyio.Entry{Path: "alb.log"})
+	p.Wait()
+
+	var report strings.Builder
+	require.Equal(map[string]int{"root": 6}, coverage.Hits())
+	require.NoError(coverage.WriteReport(&report, []string{"root", "unused"}, 10))
+	require.Equal(`hits (1 of 2 patterns):
+  6	root
+
+dead patterns (1):
+  unused
+
+unrecognized paths (top 1 of 1 shapes):
+  1	/debug
+`, report.String())
+}
+
+func TestPipelineCoverageSkipped(t *testing.T) {
+	require := require.New(t)
+
+	r, err := os.Open("testdata/alb.star")
+	require.NoError(err)
+
+	patterns, err := patterns.Load("alb.star", r)
+	require.NoError(err)
+
+	manifest := &pipeline.Manifest{
+		Path:   pipeline.ManifestName,
+		Opener: &lazyio.BufferWriter{},
+	}
+	manifest.Record("alb.log", "v1", "alb.csv", nil)
+
+	coverage := &pipeline.Coverage{}
+	p := &pipeline.Pipeline{
+		Patterns:  patterns,
+		Tokenizer: tokenizer.ALB,
+		IO:        sys.Discard(),
+		Source:    &lazyio.FileReader{Dir: "testdata/src"},
+		Result:    &lazyio.BufferWriter{},
+		Manifest:  manifest,
+		Coverage:  coverage,
+	}
+
+	p.Start(context.Background())
+	p.AddTask(context.Background(), lazyio.Entry{Path: "alb.log", Version: "v1"})
+	p.Wait()
+
+	var report strings.Builder
+	require.NoError(coverage.WriteReport(&report, []string{"root"}, 10))
+	require.Equal(`note: inputs skipped as complete, and not counted: 1
+
+hits (0 of 1 patterns):
+
+dead patterns (1):
+  root
+
+unrecognized paths (top 0 of 0 shapes):
+`, report.String())
+}
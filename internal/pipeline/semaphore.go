@@ -0,0 +1,34 @@
+package pipeline
+
+import "sync"
+
+// A semaphore limits the number of outputs held open by running tasks. Each
+// task reserves every output it might open before it starts, so tasks can't
+// deadlock waiting for each other to close outputs.
+type semaphore struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+	free int
+}
+
+func newSemaphore(n int) *semaphore {
+	s := &semaphore{free: n}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+func (s *semaphore) acquire(n int) {
+	s.mu.Lock()
+	for s.free < n {
+		s.cond.Wait()
+	}
+	s.free -= n
+	s.mu.Unlock()
+}
+
+func (s *semaphore) release(n int) {
+	s.mu.Lock()
+	s.free += n
+	s.mu.Unlock()
+	s.cond.Broadcast()
+}
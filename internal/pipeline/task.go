@@ -1,11 +1,11 @@
 package pipeline
 
 import (
-	"bufio"
+	"context"
+	"fmt"
 	"io"
 	"net/url"
 	"sort"
-	"strings"
 
 	"github.com/sjansen/carpenter/internal/lazyio"
 	"github.com/sjansen/carpenter/internal/patterns"
@@ -13,86 +13,202 @@ import (
 	"github.com/sjansen/carpenter/internal/uaparser"
 )
 
+// detectLines is the number of lines used to detect the format of an input.
+const detectLines = 10
+
 type Task struct {
 	id        string
+	version   string
+	output    string
+	committed string
+	written   bool
+	format    string
+	columns   []string
+	declared  map[string]bool
+	dropped   map[string]bool
 	patterns  *patterns.Patterns
-	tokenizer *tokenizer.Tokenizer
+	tokenizer tokenizer.Tokenizer
 	uaparser  *uaparser.Parser
+	counts    *counts
 
 	src   *lazyio.Input
-	dst   lazyio.CSV
+	dst   lazyio.Table
 	debug debug
 }
 
 type debug struct {
-	normalize    lazyio.CSV
-	parse        lazyio.CSV
+	normalize    lazyio.RowWriter
+	parse        lazyio.RowWriter
 	tokenize     lazyio.TXT
-	unrecognized lazyio.CSV
+	unrecognized lazyio.RowWriter
+}
+
+func (d *debug) Abort() {
+	d.normalize.Abort()
+	d.parse.Abort()
+	d.tokenize.Abort()
+	d.unrecognized.Abort()
 }
 
-func (d *debug) Close() {
-	d.normalize.Close()
-	d.parse.Close()
-	d.tokenize.Close()
-	d.unrecognized.Close()
+func (d *debug) Close() error {
+	var result error
+	for _, err := range []error{
+		d.normalize.Close(),
+		d.parse.Close(),
+		d.tokenize.Close(),
+		d.unrecognized.Close(),
+	} {
+		if result == nil {
+			result = err
+		}
+	}
+	return result
 }
 
-func (t *Task) Run() error {
-	r, err := t.src.Open()
+// Run transforms the input, stopping early with an error if ctx is canceled.
+// Outputs are only committed when the whole input is transformed.
+func (t *Task) Run(ctx context.Context) error {
+	r, err := t.src.Open(ctx)
 	if err != nil {
 		return err
 	}
 	defer t.src.Close()
-	defer t.dst.Close()
-	defer t.debug.Close()
 
-	buf := bufio.NewReader(r)
+	if err := t.transform(ctx, r); err != nil {
+		t.dst.Abort()
+		t.debug.Abort()
+		return err
+	}
+	if err := t.debug.Close(); err != nil {
+		t.dst.Abort()
+		return err
+	}
+	if err := t.dst.Close(); err != nil {
+		return err
+	}
+	// nothing is written for inputs without records
+	if t.written {
+		t.committed = t.output
+	}
+	return nil
+}
+
+func (t *Task) transform(ctx context.Context, r io.Reader) error {
+	lines := newLineReader(r)
+	tk := t.tokenizer
+	if tk == nil {
+		var err error
+		tk, err = t.detect(lines)
+		if err != nil || tk == nil {
+			return err
+		}
+	}
+	tk = tokenizer.Clone(tk)
+	stateful, _ := tk.(tokenizer.Stateful)
+	fallible, _ := tk.(tokenizer.Fallible)
+
 	var cols []string
-	var row []string
 	for {
-		line, err := buf.ReadString('\n')
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		line, err := lines.Next()
 		if err == io.EOF {
 			break
 		} else if err != nil {
 			return err
 		}
 
-		line = strings.TrimSpace(line)
-		if line == "" {
+		if stateful != nil && stateful.Header(line) {
 			continue
 		}
 
-		tokens := t.tokenizer.Tokenize(line)
+		var tokens map[string]string
+		if fallible != nil {
+			tokens, err = fallible.TryTokenize(line)
+			if err != nil {
+				return fmt.Errorf("tokenizer failed: %w", err)
+			}
+		} else {
+			tokens = tk.Tokenize(line)
+		}
 		if tokens == nil {
 			t.debug.tokenize.Write(line)
 			t.debug.tokenize.Write("\n")
 			continue
 		} else if cols == nil {
 			cols = t.newCols(tokens)
-			t.dst.Write(cols...)
-			row = make([]string, len(cols))
+			if err := t.dst.WriteHeader(cols...); err != nil {
+				return err
+			}
 		}
 
 		t.parseURL(tokens)
 
 		t.parseUserAgent(tokens)
 
-		for i, k := range cols {
-			if v, ok := tokens[k]; ok {
-				row[i] = v
-			} else {
-				row[i] = ""
-			}
+		t.dropUndeclared(tokens)
+
+		if err := t.dst.WriteRecord(tokens); err != nil {
+			return err
 		}
-		t.dst.Write(row...)
+		t.written = true
 	}
 
 	t.dst.Flush()
 	return t.dst.Error()
 }
 
+// detect returns the tokenizer for the format of the first lines of the
+// input, or nil if the input is empty.
+func (t *Task) detect(lines *lineReader) (tokenizer.Tokenizer, error) {
+	sample, err := lines.Peek(detectLines)
+	if err != nil || len(sample) < 1 {
+		return nil, err
+	}
+	format, tk := tokenizer.Detect(sample)
+	if tk == nil {
+		return nil, fmt.Errorf("unable to detect log format: %q", t.src.Path)
+	}
+	t.format = format
+	return tk, nil
+}
+
+// Dropped returns the sorted names of fields that were not written because
+// they weren't declared as columns.
+func (t *Task) Dropped() []string {
+	dropped := make([]string, 0, len(t.dropped))
+	for k := range t.dropped {
+		dropped = append(dropped, k)
+	}
+	sort.Strings(dropped)
+	return dropped
+}
+
+func (t *Task) dropUndeclared(tokens map[string]string) {
+	if t.columns == nil {
+		return
+	}
+	if t.declared == nil {
+		t.declared = make(map[string]bool, len(t.columns))
+		t.dropped = make(map[string]bool)
+		for _, k := range t.columns {
+			t.declared[k] = true
+		}
+	}
+	for k := range tokens {
+		if !t.declared[k] {
+			t.dropped[k] = true
+			delete(tokens, k)
+		}
+	}
+}
+
 func (t *Task) newCols(tokens map[string]string) []string {
+	if t.columns != nil {
+		return t.columns
+	}
 	cols := make([]string, 0, len(tokens)+11)
 	cols = append(cols,
 		"normalized_url",
@@ -130,7 +246,10 @@ func (t *Task) parseURL(tokens map[string]string) {
 				t.debug.normalize.Write(rawurl, err.Error())
 			} else {
 				if normalized == "" {
+					t.counts.miss(url.Path)
 					t.debug.unrecognized.Write(url.Path, rawurl)
+				} else {
+					t.counts.hit(pattern)
 				}
 				tokens["normalized_url"] = normalized
 				tokens["url_pattern"] = pattern
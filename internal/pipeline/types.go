@@ -0,0 +1,51 @@
+package pipeline
+
+import (
+	"strings"
+
+	"github.com/sjansen/carpenter/internal/lazyio"
+)
+
+var intColumns = map[string]bool{
+	// ALB
+	"client_port":           true,
+	"matched_rule_priority": true,
+	"target_port":           true,
+	// CloudFront
+	"c_port":         true,
+	"sc_content_len": true,
+	"sc_status":      true,
+	// nginx
+	"status": true,
+	// S3
+	"bytes_sent":       true,
+	"http_status":      true,
+	"object_size":      true,
+	"total_time":       true,
+	"turn_around_time": true,
+}
+
+var floatColumns = map[string]bool{
+	// CloudFront
+	"time_taken":         true,
+	"time_to_first_byte": true,
+	// nginx
+	"request_time":           true,
+	"upstream_response_time": true,
+}
+
+// columnType returns the type of the well-known numeric fields produced by
+// the built-in tokenizers.
+func columnType(col string) lazyio.ColumnType {
+	switch {
+	case intColumns[col],
+		strings.HasSuffix(col, "_bytes"),
+		strings.HasSuffix(col, "_bytes_sent"),
+		strings.HasSuffix(col, "_status_code"):
+		return lazyio.IntColumn
+	case floatColumns[col],
+		strings.HasSuffix(col, "_processing_time"):
+		return lazyio.FloatColumn
+	}
+	return lazyio.StringColumn
+}
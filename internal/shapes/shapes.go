@@ -0,0 +1,146 @@
+// Package shapes classifies the segments of URL paths, so paths that differ
+// only by IDs or other variable segments have the same shape.
+package shapes
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Shapes of variable segments. Other segments are their own shape.
+const (
+	Int  = "{int}"
+	UUID = "{uuid}"
+	Hex  = "{hex}"
+	Slug = "{slug}"
+)
+
+const (
+	intExpr  = `^[0-9]+$`
+	uuidExpr = `^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`
+	hexExpr  = `^[0-9a-fA-F]{8,}$`
+	slugExpr = `^[a-z0-9]+(?:-[a-z0-9]+){2,}$`
+
+	// notHexExpr matches segments without both digits and letters, so
+	// numbers and words like "deadbeef" aren't hex.
+	notHexExpr = `^(?:[0-9]+|[a-fA-F]+)$`
+)
+
+var (
+	intRegex  = regexp.MustCompile(intExpr)
+	uuidRegex = regexp.MustCompile(uuidExpr)
+	hexRegex  = regexp.MustCompile(hexExpr)
+	slugRegex = regexp.MustCompile(slugExpr)
+	notHex    = regexp.MustCompile(notHexExpr)
+)
+
+// Regex returns a regex matching the segments of a variable shape, and a
+// regex rejecting segments that have a different shape but would otherwise
+// match. It returns false if shape isn't variable.
+func Regex(shape string) (match, reject string, ok bool) {
+	switch shape {
+	case Int:
+		return intExpr, "", true
+	case UUID:
+		return uuidExpr, "", true
+	case Hex:
+		return hexExpr, notHexExpr, true
+	case Slug:
+		return slugExpr, uuidExpr, true
+	}
+	return "", "", false
+}
+
+// Segment returns the shape of a path segment.
+func Segment(segment string) string {
+	switch {
+	case intRegex.MatchString(segment):
+		return Int
+	case uuidRegex.MatchString(segment):
+		return UUID
+	case hexRegex.MatchString(segment) && !notHex.MatchString(segment):
+		return Hex
+	case slugRegex.MatchString(segment):
+		return Slug
+	}
+	return segment
+}
+
+// Path returns the shape of a path, such as "/users/{int}/posts/{slug}".
+func Path(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = Segment(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// A Group is a set of paths with the same shape, ignoring trailing slashes.
+type Group struct {
+	// Shape is the shape of the paths, without a trailing slash.
+	Shape string
+	Count int
+	// Samples are the first few distinct paths.
+	Samples []string
+	// Slash and NoSlash report whether any paths had or lacked a trailing
+	// slash.
+	Slash   bool
+	NoSlash bool
+}
+
+// A Grouper groups paths by shape.
+type Grouper struct {
+	// Samples is the number of distinct paths kept by each group.
+	Samples int
+
+	groups map[string]*Group
+}
+
+// Add adds a path to the group for its shape.
+func (g *Grouper) Add(path string) {
+	if g.groups == nil {
+		g.groups = make(map[string]*Group)
+	}
+	shape := Path(path)
+	slash := strings.HasSuffix(shape, "/") && shape != "/"
+	if slash {
+		shape = shape[:len(shape)-1]
+	}
+
+	group, ok := g.groups[shape]
+	if !ok {
+		group = &Group{Shape: shape}
+		g.groups[shape] = group
+	}
+	group.Count++
+	if slash {
+		group.Slash = true
+	} else {
+		group.NoSlash = true
+	}
+	if len(group.Samples) < g.Samples {
+		for _, sample := range group.Samples {
+			if sample == path {
+				return
+			}
+		}
+		group.Samples = append(group.Samples, path)
+	}
+}
+
+// Groups returns the groups with the most paths first.
+func (g *Grouper) Groups() []*Group {
+	groups := make([]*Group, 0, len(g.groups))
+	for _, group := range g.groups {
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		a, b := groups[i], groups[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Shape < b.Shape
+	})
+	return groups
+}
@@ -0,0 +1,54 @@
+package shapes_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/sjansen/carpenter/internal/shapes"
+)
+
+func TestSegment(t *testing.T) {
+	for segment, expected := range map[string]string{
+		"":                                     "",
+		"42":                                   shapes.Int,
+		"c0ffee00":                             shapes.Hex,
+		"9F86D081884C7D659A2FEAA0C55AD015":     shapes.Hex,
+		"123e4567-e89b-12d3-a456-426614174000": shapes.UUID,
+		"my-first-blog-post":                   shapes.Slug,
+		"well-known":                           "well-known",
+		"deadbeef":                             "deadbeef",
+		"users":                                "users",
+	} {
+		require.Equal(t, expected, shapes.Segment(segment), segment)
+	}
+}
+
+func TestPath(t *testing.T) {
+	require.Equal(t,
+		"/users/{int}/posts/{slug}/",
+		shapes.Path("/users/42/posts/my-first-blog-post/"),
+	)
+}
+
+func TestGrouper(t *testing.T) {
+	require := require.New(t)
+
+	g := &shapes.Grouper{Samples: 2}
+	for _, path := range []string{
+		"/users/1", "/users/2/", "/users/1", "/users/3", "/about",
+	} {
+		g.Add(path)
+	}
+
+	groups := g.Groups()
+	require.Len(groups, 2)
+	require.Equal(&shapes.Group{
+		Shape:   "/users/{int}",
+		Count:   4,
+		Samples: []string{"/users/1", "/users/2/"},
+		Slash:   true,
+		NoSlash: true,
+	}, groups[0])
+	require.Equal("/about", groups[1].Shape)
+}
@@ -3,6 +3,7 @@ package sys
 import (
 	"io"
 	"io/ioutil"
+	"strings"
 
 	"go.uber.org/zap"
 
@@ -13,6 +14,7 @@ import (
 type IO struct {
 	Log *zap.SugaredLogger
 
+	Stdin  io.Reader
 	Stdout io.Writer
 	Stderr io.Writer
 }
@@ -20,6 +22,7 @@ type IO struct {
 func Discard() *IO {
 	return &IO{
 		Log:    logger.Discard(),
+		Stdin:  strings.NewReader(""),
 		Stdout: ioutil.Discard,
 		Stderr: ioutil.Discard,
 	}
@@ -0,0 +1,90 @@
+package tokenizer
+
+import (
+	"net/url"
+	"strings"
+)
+
+var _ Stateful = &cloudFront{}
+
+// CloudFront tokenizes standard access logs, using the "#Fields" header of
+// each input to name columns.
+var CloudFront Stateful = newCloudFront(strings.Fields(
+	"date time x-edge-location sc-bytes c-ip cs-method cs(Host) cs-uri-stem " +
+		"sc-status cs(Referer) cs(User-Agent) cs-uri-query cs(Cookie) " +
+		"x-edge-result-type x-edge-request-id x-host-header cs-protocol " +
+		"cs-bytes time-taken x-forwarded-for ssl-protocol ssl-cipher " +
+		"x-edge-response-result-type cs-protocol-version fle-status " +
+		"fle-encrypted-fields c-port time-to-first-byte " +
+		"x-edge-detailed-result-type sc-content-type sc-content-len " +
+		"sc-range-start sc-range-end",
+))
+
+var cloudFrontRenames = map[string]string{
+	"cs_user_agent": "user_agent",
+}
+
+var cloudFrontReplacer = strings.NewReplacer("(", "_", ")", "", "-", "_")
+
+type cloudFront struct {
+	fields []string
+}
+
+func newCloudFront(fields []string) *cloudFront {
+	keys := make([]string, len(fields))
+	for i, field := range fields {
+		key := cloudFrontReplacer.Replace(strings.ToLower(field))
+		if renamed, ok := cloudFrontRenames[key]; ok {
+			key = renamed
+		}
+		keys[i] = key
+	}
+	return &cloudFront{fields: keys}
+}
+
+func (t *cloudFront) Clone() Stateful {
+	return &cloudFront{fields: t.fields}
+}
+
+func (t *cloudFront) Header(line string) bool {
+	if !strings.HasPrefix(line, "#") {
+		return false
+	}
+	if strings.HasPrefix(line, "#Fields:") {
+		fields := strings.Fields(line[len("#Fields:"):])
+		t.fields = newCloudFront(fields).fields
+	}
+	return true
+}
+
+func (t *cloudFront) Tokenize(line string) map[string]string {
+	if strings.HasPrefix(line, "#") {
+		return nil
+	}
+
+	values := strings.Split(line, "\t")
+	if len(values) != len(t.fields) {
+		return nil
+	}
+
+	result := make(map[string]string, len(values)+1)
+	for i, key := range t.fields {
+		result[key] = values[i]
+	}
+
+	if stem, ok := result["cs_uri_stem"]; ok {
+		if query := result["cs_uri_query"]; query != "" && query != "-" {
+			result["request_url"] = stem + "?" + query
+		} else {
+			result["request_url"] = stem
+		}
+	}
+	if ua, ok := result["user_agent"]; ok {
+		// CloudFront percent-encodes spaces and other special characters.
+		if decoded, err := url.PathUnescape(ua); err == nil {
+			result["user_agent"] = decoded
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,56 @@
+package tokenizer
+
+import "sort"
+
+var formats = map[string]Tokenizer{
+	"alb":        ALB,
+	"cloudfront": CloudFront,
+	"json":       &JSON{},
+	"nginx":      Nginx,
+	"s3access":   S3Access,
+}
+
+// Formats returns the names of the built-in tokenizers.
+func Formats() []string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lookup returns the built-in tokenizer for format.
+func Lookup(format string) (Tokenizer, bool) {
+	t, ok := formats[format]
+	return t, ok
+}
+
+// Detect returns the built-in tokenizer that recognizes the most lines, or
+// nil if no tokenizer recognizes any of them.
+func Detect(lines []string) (string, Tokenizer) {
+	var best string
+	var bestCount int
+	for _, name := range Formats() {
+		t := Clone(formats[name])
+		stateful, _ := t.(Stateful)
+
+		count := 0
+		for _, line := range lines {
+			if stateful != nil && stateful.Header(line) {
+				continue
+			}
+			if t.Tokenize(line) != nil {
+				count++
+			}
+		}
+		if count > bestCount {
+			best = name
+			bestCount = count
+		}
+	}
+	if best == "" {
+		return "", nil
+	}
+	return best, formats[best]
+}
@@ -0,0 +1,74 @@
+package tokenizer
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+var _ Tokenizer = &JSON{}
+
+// A JSON tokenizer parses lines containing JSON objects. Nested objects are
+// flattened, so {"http": {"url": "/"}} produces the field "http.url".
+type JSON struct {
+	// Fields renames flattened fields, for example from "http.url" to
+	// "request_url".
+	Fields map[string]string
+}
+
+func (t *JSON) Tokenize(line string) map[string]string {
+	if !strings.HasPrefix(line, "{") {
+		return nil
+	}
+
+	dec := json.NewDecoder(strings.NewReader(line))
+	dec.UseNumber()
+
+	var obj map[string]interface{}
+	if err := dec.Decode(&obj); err != nil || dec.More() {
+		return nil
+	}
+
+	result := make(map[string]string, len(obj))
+	t.flatten(result, "", obj)
+	return result
+}
+
+func (t *JSON) flatten(result map[string]string, prefix string, obj map[string]interface{}) {
+	for k, v := range obj {
+		key := prefix + k
+		if nested, ok := v.(map[string]interface{}); ok {
+			t.flatten(result, key+".", nested)
+			continue
+		}
+
+		var value string
+		switch v := v.(type) {
+		case nil:
+			continue
+		case string:
+			value = v
+		case json.Number:
+			value = v.String()
+		case bool:
+			if v {
+				value = "true"
+			} else {
+				value = "false"
+			}
+		default:
+			var buf bytes.Buffer
+			enc := json.NewEncoder(&buf)
+			enc.SetEscapeHTML(false)
+			if err := enc.Encode(v); err != nil {
+				continue
+			}
+			value = strings.TrimSpace(buf.String())
+		}
+
+		if renamed, ok := t.Fields[key]; ok {
+			key = renamed
+		}
+		result[key] = value
+	}
+}
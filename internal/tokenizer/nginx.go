@@ -0,0 +1,139 @@
+package tokenizer
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Nginx tokenizes access logs in nginx's predefined "combined" format.
+var Nginx = MustNginx(
+	`$remote_addr - $remote_user [$time_local] ` +
+		`"$request" $status $body_bytes_sent ` +
+		`"$http_referer" "$http_user_agent"`,
+)
+
+var nginxRenames = map[string]string{
+	"http_user_agent": "user_agent",
+	"request_method":  "request_verb",
+	"request_uri":     "request_url",
+	"server_protocol": "request_proto",
+}
+
+// MustNginx is like NewNginx but panics if format is invalid.
+func MustNginx(format string) *Regex {
+	t, err := NewNginx(format)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// NewNginx returns a tokenizer for logs written using format, the string
+// argument of an nginx log_format directive.
+//
+// Variables are used as field names, except that $request is split into
+// request_verb, request_url, and request_proto, and variables such as
+// $http_user_agent and $request_uri are renamed to match the fields
+// produced by other tokenizers.
+func NewNginx(format string) (*Regex, error) {
+	var expr strings.Builder
+	expr.WriteString("^")
+
+	seen := map[string]bool{}
+	for rest := format; rest != ""; {
+		idx := strings.IndexByte(rest, '$')
+		if idx < 0 {
+			expr.WriteString(regexp.QuoteMeta(rest))
+			break
+		}
+		expr.WriteString(regexp.QuoteMeta(rest[:idx]))
+		rest = rest[idx+1:]
+
+		name, n, err := nginxVariable(rest)
+		if err != nil {
+			return nil, fmt.Errorf("invalid log format: %s: %q", err, format)
+		}
+		rest = rest[n:]
+
+		value := ".*"
+		if rest != "" && rest[0] != '$' {
+			_, size := utf8.DecodeRuneInString(rest)
+			value = "[^" + regexp.QuoteMeta(rest[:size]) + "]*"
+		}
+
+		switch {
+		case seen[name]:
+			expr.WriteString("(?:" + value + ")")
+		case name == "request":
+			word := "[^ ]*"
+			if strings.HasPrefix(value, "[^") {
+				word = "[^ " + value[2:]
+			}
+			expr.WriteString(
+				"(?P<request_verb>" + word + ")" +
+					"(?: (?P<request_url>" + word + "))?" +
+					"(?: (?P<request_proto>" + value + "))?",
+			)
+		default:
+			key := name
+			if renamed, ok := nginxRenames[name]; ok {
+				key = renamed
+			}
+			expr.WriteString("(?P<" + key + ">" + value + ")")
+		}
+		seen[name] = true
+	}
+
+	expr.WriteString("$")
+	regex, err := regexp.Compile(expr.String())
+	if err != nil {
+		return nil, err
+	}
+	return &Regex{regex}, nil
+}
+
+// nginxVariable returns the name of the variable at the start of s, which
+// should immediately follow a "$", and the number of bytes it occupies.
+func nginxVariable(s string) (string, int, error) {
+	if strings.HasPrefix(s, "{") {
+		end := strings.IndexByte(s, '}')
+		if end < 0 {
+			return "", 0, fmt.Errorf("unterminated variable")
+		}
+		name := s[1:end]
+		if !isNginxName(name) {
+			return "", 0, fmt.Errorf("invalid variable name %q", name)
+		}
+		return name, end + 1, nil
+	}
+
+	n := 0
+	for n < len(s) && isNginxNameByte(s[n]) {
+		n++
+	}
+	if n == 0 {
+		return "", 0, fmt.Errorf("missing variable name")
+	}
+	return s[:n], n, nil
+}
+
+func isNginxName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isNginxNameByte(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isNginxNameByte(c byte) bool {
+	return c == '_' ||
+		('0' <= c && c <= '9') ||
+		('a' <= c && c <= 'z') ||
+		('A' <= c && c <= 'Z')
+}
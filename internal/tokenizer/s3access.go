@@ -0,0 +1,34 @@
+package tokenizer
+
+import "regexp"
+
+// S3Access tokenizes S3 server access logs.
+var S3Access = &Regex{regexp.MustCompile(`^` +
+	`(?P<bucket_owner>[^ ]*) ` +
+	`(?P<bucket>[^ ]*) ` +
+	`\[(?P<time>[^\]]*)\] ` +
+	`(?P<remote_ip>[^ ]*) ` +
+	`(?P<requester>[^ ]*) ` +
+	`(?P<request_id>[^ ]*) ` +
+	`(?P<operation>[^ ]*) ` +
+	`(?P<key>[^ ]*) ` +
+	`"(?P<request_verb>[^ "]*) ?(?P<request_url>[^ "]*) ?(?P<request_proto>[^"]*)" ` +
+	`(?P<http_status>[-0-9]*) ` +
+	`(?P<error_code>[^ ]*) ` +
+	`(?P<bytes_sent>[-0-9]*) ` +
+	`(?P<object_size>[-0-9]*) ` +
+	`(?P<total_time>[-0-9]*) ` +
+	`(?P<turn_around_time>[-0-9]*) ` +
+	`"(?P<referrer>[^"]*)" ` +
+	`"(?P<user_agent>[^"]*)" ` +
+	`(?P<version_id>[^ ]*)` +
+	`(?: (?P<host_id>[^ ]*) ` +
+	`(?P<signature_version>[^ ]*) ` +
+	`(?P<cipher_suite>[^ ]*) ` +
+	`(?P<authentication_type>[^ ]*) ` +
+	`(?P<host_header>[^ ]*) ` +
+	`(?P<tls_version>[^ ]*))?` +
+	`(?: (?P<access_point_arn>[^ ]*))?` +
+	`(?: (?P<acl_required>[^ ]*))?` +
+	`(?:.*?)$`,
+)}
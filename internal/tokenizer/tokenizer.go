@@ -1,14 +1,91 @@
 package tokenizer
 
 import (
+	"fmt"
 	"regexp"
+	"regexp/syntax"
 )
 
-type Tokenizer struct {
+var _ Tokenizer = &Regex{}
+
+// A Tokenizer splits a log line into named fields.
+type Tokenizer interface {
+	// Tokenize returns nil when line is not in the expected format.
+	Tokenize(line string) map[string]string
+}
+
+// A Stateful tokenizer is configured by header lines at the start of each
+// input, so each input must be tokenized by its own clone.
+type Stateful interface {
+	Tokenizer
+	Clone() Stateful
+	// Header reports whether line was consumed as a header.
+	Header(line string) bool
+}
+
+// A Fallible tokenizer can fail for reasons other than a line in an
+// unexpected format, such as a bug in a user-defined function.
+type Fallible interface {
+	Tokenizer
+	// TryTokenize returns nil without an error when line is not in the
+	// expected format.
+	TryTokenize(line string) (map[string]string, error)
+}
+
+// Clone returns a tokenizer that is safe to use for a single input.
+func Clone(t Tokenizer) Tokenizer {
+	if s, ok := t.(Stateful); ok {
+		return s.Clone()
+	}
+	return t
+}
+
+// A Regex tokenizer uses the named groups of a regular expression as fields.
+type Regex struct {
 	*regexp.Regexp
 }
 
-func (t *Tokenizer) Tokenize(line string) map[string]string {
+// NewRegex returns a tokenizer for expr, after renaming the named groups
+// that are keys of fields to the corresponding values.
+func NewRegex(expr string, fields map[string]string) (*Regex, error) {
+	re, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]bool, len(fields))
+	renameGroups(re, fields, found)
+	for name := range fields {
+		if !found[name] {
+			return nil, fmt.Errorf("unknown group: %q", name)
+		}
+	}
+
+	regex, err := regexp.Compile(re.String())
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range regex.SubexpNames() {
+		if name != "" {
+			return &Regex{regex}, nil
+		}
+	}
+	return nil, fmt.Errorf("no named groups: %q", expr)
+}
+
+func renameGroups(re *syntax.Regexp, fields map[string]string, found map[string]bool) {
+	if re.Op == syntax.OpCapture && re.Name != "" {
+		if renamed, ok := fields[re.Name]; ok {
+			found[re.Name] = true
+			re.Name = renamed
+		}
+	}
+	for _, sub := range re.Sub {
+		renameGroups(sub, fields, found)
+	}
+}
+
+func (t *Regex) Tokenize(line string) map[string]string {
 	values := t.FindStringSubmatch(line)
 	if values == nil {
 		return nil
@@ -17,13 +94,15 @@ func (t *Tokenizer) Tokenize(line string) map[string]string {
 	names := t.SubexpNames()[1:]
 	result := make(map[string]string, len(names))
 	for i, key := range names {
-		result[key] = values[i+1]
+		if key != "" {
+			result[key] = values[i+1]
+		}
 	}
 
 	return result
 }
 
-var ALB = &Tokenizer{regexp.MustCompile(`^` +
+var ALB = &Regex{regexp.MustCompile(`^` +
 	`(?P<type>[^ ]*) ` +
 	`(?P<timestamp>[^ ]*) ` +
 	`(?P<lb>[^ ]*) ` +
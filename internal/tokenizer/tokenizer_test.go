@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -14,11 +15,151 @@ import (
 )
 
 func TestALB(t *testing.T) {
-	files, _ := filepath.Glob("testdata/alb-*.txt")
+	testTokenizer(t, "alb", tokenizer.ALB)
+}
+
+func TestCloudFront(t *testing.T) {
+	testTokenizer(t, "cloudfront", tokenizer.CloudFront)
+}
+
+func TestJSON(t *testing.T) {
+	testTokenizer(t, "json", &tokenizer.JSON{})
+}
+
+func TestJSONFields(t *testing.T) {
+	require := require.New(t)
+
+	tk := &tokenizer.JSON{
+		Fields: map[string]string{
+			"http.url":                "request_url",
+			"http.headers.user-agent": "user_agent",
+		},
+	}
+
+	actual := tk.Tokenize(`{"http": {"url": "/", "headers": {"user-agent": "curl/7.46.0"}}}`)
+	require.Equal(map[string]string{
+		"request_url": "/",
+		"user_agent":  "curl/7.46.0",
+	}, actual)
+
+	for _, line := range []string{
+		`["/"]`,
+		`{"http": `,
+		`{"http": {}} {"http": {}}`,
+	} {
+		require.Nil(tk.Tokenize(line), line)
+	}
+}
+
+func TestNginx(t *testing.T) {
+	testTokenizer(t, "nginx", tokenizer.Nginx)
+}
+
+func TestNewNginx(t *testing.T) {
+	require := require.New(t)
+
+	for _, tc := range []struct {
+		format   string
+		line     string
+		expected map[string]string
+	}{{
+		format: `$remote_addr [${time_local}] "$request_method $request_uri $server_protocol" $status`,
+		line:   `192.0.2.1 [02/Jul/2018:22:23:00 +0000] "GET /foo?bar=baz HTTP/1.1" 200`,
+		expected: map[string]string{
+			"remote_addr":   "192.0.2.1",
+			"time_local":    "02/Jul/2018:22:23:00 +0000",
+			"request_verb":  "GET",
+			"request_url":   "/foo?bar=baz",
+			"request_proto": "HTTP/1.1",
+			"status":        "200",
+		},
+	}, {
+		format: `$host:$server_port $request_time "$http_user_agent" $status $status`,
+		line:   `example.com:443 0.012 "Mozilla/5.0 (compatible)" 404 404`,
+		expected: map[string]string{
+			"host":         "example.com",
+			"server_port":  "443",
+			"request_time": "0.012",
+			"user_agent":   "Mozilla/5.0 (compatible)",
+			"status":       "404",
+		},
+	}, {
+		format:   `[$time_local] $status`,
+		line:     `02/Jul/2018:22:23:00 +0000 200`,
+		expected: nil,
+	}} {
+		tk, err := tokenizer.NewNginx(tc.format)
+		require.NoError(err, tc.format)
+
+		actual := tk.Tokenize(tc.line)
+		require.Equal(tc.expected, actual, tc.format)
+	}
+
+	for _, format := range []string{
+		`$remote_addr ${time_local`,
+		`$remote_addr ${time-local}`,
+		`$remote_addr $ $status`,
+	} {
+		_, err := tokenizer.NewNginx(format)
+		require.Error(err, format)
+	}
+}
+
+func TestS3Access(t *testing.T) {
+	testTokenizer(t, "s3access", tokenizer.S3Access)
+}
+
+func TestNewRegex(t *testing.T) {
+	require := require.New(t)
+
+	tk, err := tokenizer.NewRegex(
+		`^(?i)(?P<verb>[a-z]+) (?P<path>[^ ]+) (\d+)$`,
+		map[string]string{"verb": "request_verb", "path": "request_url"},
+	)
+	require.NoError(err)
+
+	actual := tk.Tokenize("get /foo 42")
+	require.Equal(map[string]string{
+		"request_verb": "get",
+		"request_url":  "/foo",
+	}, actual)
+	require.Nil(tk.Tokenize("get /foo bar"))
+
+	_, err = tokenizer.NewRegex(`^(?P<verb>[A-Z]+)`, map[string]string{"path": "request_url"})
+	require.EqualError(err, `unknown group: "path"`)
+
+	_, err = tokenizer.NewRegex(`^([A-Z]+)`, nil)
+	require.Error(err)
+
+	_, err = tokenizer.NewRegex(`^(?P<verb>[A-Z]+`, nil)
+	require.Error(err)
+}
+
+func TestDetect(t *testing.T) {
+	for _, format := range tokenizer.Formats() {
+		files, _ := filepath.Glob("testdata/" + format + "-*.txt")
+		for _, tc := range files {
+			data, err := ioutil.ReadFile(tc)
+			require.NoError(t, err)
+			lines := strings.Split(string(bytes.TrimSpace(data)), "\n")
+
+			actual, tk := tokenizer.Detect(lines)
+			require.Equal(t, format, actual, tc)
+			require.NotNil(t, tk, tc)
+		}
+	}
+
+	actual, tk := tokenizer.Detect([]string{"Spoon!"})
+	require.Equal(t, "", actual)
+	require.Nil(t, tk)
+}
+
+func testTokenizer(t *testing.T, format string, tk tokenizer.Tokenizer) {
+	files, _ := filepath.Glob("testdata/" + format + "-*.txt")
 	for _, tc := range files {
 		tc := tc
 		prefix := tc[:len(tc)-4]
-		t.Run(filepath.Base(prefix)[4:], func(t *testing.T) {
+		t.Run(filepath.Base(prefix)[len(format)+1:], func(t *testing.T) {
 			assert := assert.New(t)
 			require := require.New(t)
 
@@ -31,9 +172,17 @@ func TestALB(t *testing.T) {
 
 			data, err = ioutil.ReadFile(tc)
 			require.NoError(err)
-			line := string(bytes.TrimSpace(data))
+			lines := strings.Split(string(bytes.TrimSpace(data)), "\n")
+
+			tk := tokenizer.Clone(tk)
+			if s, ok := tk.(tokenizer.Stateful); ok {
+				for len(lines) > 1 && s.Header(lines[0]) {
+					lines = lines[1:]
+				}
+			}
+			require.Len(lines, 1)
 
-			actual := tokenizer.ALB.Tokenize(line)
+			actual := tk.Tokenize(lines[0])
 			if !assert.Equal(expected, actual) {
 				data, err := json.MarshalIndent(actual, "", "  ")
 				require.NoError(err)